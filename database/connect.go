@@ -41,6 +41,24 @@ func ConnectDB() {
 		&models.User{},
 		&models.Cameras{},
 		&models.DeviceType{},
+		&models.Notification{},
+		&models.EscalationPolicy{},
+		&models.EscalationStep{},
+		&models.Incident{},
+		&models.UserContact{},
+		&models.OnCallSchedule{},
+		&models.OnCallMember{},
+		&models.OnCallOverride{},
+		&models.QuietHours{},
+		&models.CheckResult{},
+		&models.CheckRollup{},
+		&models.MaintenanceWindow{},
+		&models.ReportDefinition{},
+		&models.StatusPage{},
+		&models.StatusPageComponent{},
+		&models.HostGroup{},
+		&models.HostTag{},
+		&models.AlertRoute{},
 	)
 	if err != nil {
 		log.Fatalf("Migration failed: %v", err)
@@ -129,11 +147,14 @@ func createMethods() {
 	} else {
 		log.Println("Methods already exist in the table")
 	}
-	if len(alertchannels) == 0 {
+	// Default channels are optional now that channels can be managed through the API
+	if config.Config("SEED_DEFAULT_CHANNELS") == "false" {
+		log.Println("Skipping default alert channels")
+	} else if len(alertchannels) == 0 {
 		defaultChannels := []models.AlertChannel{
-			{Name: "telegram"},
-			{Name: "mail"},
-			{Name: "hook"},
+			{Name: "telegram", Provider: "telegram"},
+			{Name: "mail", Provider: "mail"},
+			{Name: "hook", Provider: "hook"},
 		}
 		for _, channel := range defaultChannels {
 			result := DB.Create(&channel)
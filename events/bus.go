@@ -0,0 +1,223 @@
+// Package events is the in-process bus the checker publishes to. History, alerting,
+// metrics and live streaming each subscribe independently.
+package events
+
+import (
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Envelope wraps a published event with what every subscriber needs to route it
+type Envelope struct {
+	ID         uint64    `json:"id"`
+	Type       string    `json:"type"`
+	Time       time.Time `json:"time"`
+	HostID     uint      `json:"host_id,omitempty"`
+	HostName   string    `json:"host_name,omitempty"`
+	DeviceType string    `json:"device_type,omitempty"`
+	Data       Event     `json:"data"`
+}
+
+type subscriber interface {
+	deliver(Envelope)
+	close()
+}
+
+// Bus fans events out to its subscribers; publishing never blocks
+type Bus struct {
+	mu     sync.RWMutex
+	subs   map[subscriber]struct{}
+	named  []*Handler
+	nextID uint64
+}
+
+func NewBus() *Bus {
+	return &Bus{subs: map[subscriber]struct{}{}}
+}
+
+// Default is the bus shared by the scheduler and the API
+var Default = NewBus()
+
+// Publish stamps the event and hands it to every subscriber
+func (b *Bus) Publish(event Event) {
+	ref := event.Subject()
+	envelope := Envelope{
+		ID:         atomic.AddUint64(&b.nextID, 1),
+		Type:       event.Type(),
+		Time:       time.Now(),
+		HostID:     ref.ID,
+		HostName:   ref.Name,
+		DeviceType: ref.DeviceType,
+		Data:       event,
+	}
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for sub := range b.subs {
+		sub.deliver(envelope)
+	}
+}
+
+// Publish sends the event on the default bus
+func Publish(event Event) {
+	Default.Publish(event)
+}
+
+func (b *Bus) remove(sub subscriber) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if _, ok := b.subs[sub]; ok {
+		delete(b.subs, sub)
+		sub.close()
+	}
+}
+
+// Subscription is a lossy subscriber for clients that may fall behind, such as live streams
+type Subscription struct {
+	C       <-chan Envelope
+	ch      chan Envelope
+	dropped uint64
+	bus     *Bus
+}
+
+// Subscribe registers a channel subscriber with room for buffer pending events;
+// events that do not fit are dropped rather than delaying the publisher
+func (b *Bus) Subscribe(buffer int) *Subscription {
+	ch := make(chan Envelope, buffer)
+	sub := &Subscription{C: ch, ch: ch, bus: b}
+	b.mu.Lock()
+	b.subs[sub] = struct{}{}
+	b.mu.Unlock()
+	return sub
+}
+
+func (s *Subscription) deliver(envelope Envelope) {
+	select {
+	case s.ch <- envelope:
+	default:
+		atomic.AddUint64(&s.dropped, 1)
+	}
+}
+
+func (s *Subscription) close() {
+	close(s.ch)
+}
+
+// Dropped counts the events discarded because the subscriber fell behind
+func (s *Subscription) Dropped() uint64 {
+	return atomic.LoadUint64(&s.dropped)
+}
+
+// Close unsubscribes and closes C
+func (s *Subscription) Close() {
+	s.bus.remove(s)
+}
+
+// backlogWarning is the queue length at which a handler is reported as slow
+const backlogWarning = 1000
+
+// Handler is a reliable subscriber: every event is queued without bound and handled in
+// publish order on the handler's own goroutine, so a slow handler delays only itself
+type Handler struct {
+	Name    string
+	handle  func(Envelope)
+	mu      sync.Mutex
+	queue   []Envelope
+	wake    chan struct{}
+	done    chan struct{}
+	handled uint64
+	warned  bool
+}
+
+// Handle starts a named handler that receives every event published from now on
+func (b *Bus) Handle(name string, handle func(Envelope)) *Handler {
+	h := &Handler{Name: name, handle: handle, wake: make(chan struct{}, 1), done: make(chan struct{})}
+	b.mu.Lock()
+	b.subs[h] = struct{}{}
+	b.named = append(b.named, h)
+	b.mu.Unlock()
+	go h.run()
+	return h
+}
+
+// Handle starts a named handler on the default bus
+func Handle(name string, handle func(Envelope)) *Handler {
+	return Default.Handle(name, handle)
+}
+
+func (h *Handler) deliver(envelope Envelope) {
+	h.mu.Lock()
+	h.queue = append(h.queue, envelope)
+	if len(h.queue) >= backlogWarning && !h.warned {
+		h.warned = true
+		log.Printf("Event handler %s is falling behind, %d events queued", h.Name, len(h.queue))
+	}
+	h.mu.Unlock()
+	select {
+	case h.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (h *Handler) close() {
+	close(h.done)
+}
+
+func (h *Handler) run() {
+	for {
+		select {
+		case <-h.wake:
+		case <-h.done:
+			return
+		}
+		for {
+			h.mu.Lock()
+			if len(h.queue) == 0 {
+				h.warned = false
+				h.mu.Unlock()
+				break
+			}
+			batch := h.queue
+			h.queue = nil
+			h.mu.Unlock()
+
+			for _, envelope := range batch {
+				h.safeHandle(envelope)
+			}
+		}
+	}
+}
+
+// safeHandle keeps a panicking handler from taking down the process or its queue
+func (h *Handler) safeHandle(envelope Envelope) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("Event handler %s panicked on %s event %d: %v", h.Name, envelope.Type, envelope.ID, r)
+		}
+	}()
+	h.handle(envelope)
+	atomic.AddUint64(&h.handled, 1)
+}
+
+// HandlerStats describes the queue of one reliable handler
+type HandlerStats struct {
+	Name    string
+	Pending int
+	Handled uint64
+}
+
+// Stats reports the queue of every reliable handler
+func (b *Bus) Stats() []HandlerStats {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	stats := make([]HandlerStats, 0, len(b.named))
+	for _, h := range b.named {
+		h.mu.Lock()
+		pending := len(h.queue)
+		h.mu.Unlock()
+		stats = append(stats, HandlerStats{Name: h.Name, Pending: pending, Handled: atomic.LoadUint64(&h.handled)})
+	}
+	return stats
+}
@@ -0,0 +1,121 @@
+package events
+
+import (
+	"alerting-app/models"
+	"time"
+)
+
+// Event types
+const (
+	TypeCheckCompleted       = "check.completed"
+	TypeStateChanged         = "state.changed"
+	TypeIncidentOpened       = "incident.opened"
+	TypeIncidentAcknowledged = "incident.acknowledged"
+	TypeIncidentResolved     = "incident.resolved"
+	TypeNotificationSent     = "notification.sent"
+	TypeTickStarted          = "tick.started"
+	TypeTickFinished         = "tick.finished"
+)
+
+// HostRef identifies the host an event is about
+type HostRef struct {
+	ID         uint
+	Name       string
+	DeviceType string
+}
+
+// Event is implemented by every typed event below
+type Event interface {
+	Type() string
+	Subject() HostRef
+}
+
+func hostRef(host *models.Host) HostRef {
+	return HostRef{ID: host.ID, Name: host.Name, DeviceType: host.DeviceTypeName}
+}
+
+// CheckCompleted is published after every check, before the host state is evaluated
+type CheckCompleted struct {
+	Host       models.Host   `json:"-"`
+	CheckedAt  time.Time     `json:"checked_at"`
+	Status     string        `json:"status"` // "up" or "down"
+	Method     string        `json:"method"`
+	Latency    time.Duration `json:"-"`
+	LatencyMs  float64       `json:"latency_ms"`
+	StatusCode int           `json:"status_code"`
+	Error      string        `json:"error"`
+	Duration   time.Duration `json:"-"` // wall time of the whole check
+}
+
+func (e CheckCompleted) Type() string     { return TypeCheckCompleted }
+func (e CheckCompleted) Subject() HostRef { return hostRef(&e.Host) }
+
+// StateChanged is published when a host is alerted as down or recovers.
+// Host is the state right after the change.
+type StateChanged struct {
+	Host models.Host `json:"host"`
+	From string      `json:"from"`
+	To   string      `json:"to"`
+}
+
+func (e StateChanged) Type() string     { return TypeStateChanged }
+func (e StateChanged) Subject() HostRef { return hostRef(&e.Host) }
+
+type IncidentOpened struct {
+	Incident   models.Incident `json:"incident"`
+	DeviceType string          `json:"-"`
+}
+
+func (e IncidentOpened) Type() string { return TypeIncidentOpened }
+func (e IncidentOpened) Subject() HostRef {
+	return HostRef{ID: e.Incident.HostID, Name: e.Incident.HostName, DeviceType: e.DeviceType}
+}
+
+type IncidentAcknowledged struct {
+	Incident   models.Incident `json:"incident"`
+	DeviceType string          `json:"-"`
+}
+
+func (e IncidentAcknowledged) Type() string { return TypeIncidentAcknowledged }
+func (e IncidentAcknowledged) Subject() HostRef {
+	return HostRef{ID: e.Incident.HostID, Name: e.Incident.HostName, DeviceType: e.DeviceType}
+}
+
+type IncidentResolved struct {
+	Incident   models.Incident `json:"incident"`
+	DeviceType string          `json:"-"`
+}
+
+func (e IncidentResolved) Type() string { return TypeIncidentResolved }
+func (e IncidentResolved) Subject() HostRef {
+	return HostRef{ID: e.Incident.HostID, Name: e.Incident.HostName, DeviceType: e.DeviceType}
+}
+
+// NotificationSent is published after every delivery attempt; Notification.Status tells
+// whether it was delivered, failed for good or will be retried
+type NotificationSent struct {
+	Notification models.Notification `json:"notification"`
+	Error        error               `json:"-"`
+	DeviceType   string              `json:"-"`
+}
+
+func (e NotificationSent) Type() string { return TypeNotificationSent }
+func (e NotificationSent) Subject() HostRef {
+	return HostRef{ID: e.Notification.HostID, Name: e.Notification.HostName, DeviceType: e.DeviceType}
+}
+
+// TickStarted and TickFinished bracket one run of the check scheduler
+type TickStarted struct{}
+
+func (e TickStarted) Type() string     { return TypeTickStarted }
+func (e TickStarted) Subject() HostRef { return HostRef{} }
+
+type TickFinished struct {
+	Started  time.Time     `json:"started"`
+	Duration time.Duration `json:"duration"`
+	Active   int           `json:"active"`
+	Checked  int           `json:"checked"`
+}
+
+func (e TickFinished) Type() string     { return TypeTickFinished }
+func (e TickFinished) Subject() HostRef { return HostRef{} }
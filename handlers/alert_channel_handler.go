@@ -0,0 +1,303 @@
+package handlers
+
+import (
+	"alerting-app/database"
+	"alerting-app/jobs"
+	"alerting-app/models"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+const secretMask = "****"
+
+// channelConfigSpec lists, per provider, which Config fields are required and which hold secrets (1-based)
+var channelConfigSpec = map[string]struct {
+	required []int
+	secrets  []int
+}{
+	"telegram": {required: []int{1, 2}, secrets: []int{2}},
+	"mail":     {required: []int{1}, secrets: []int{3}},
+	"hook":     {required: []int{1}, secrets: []int{2}},
+	"slack":    {required: []int{1}, secrets: []int{1}},
+	"teams":    {required: []int{1}, secrets: []int{1}},
+	"discord":  {required: []int{1}, secrets: []int{1}},
+}
+
+func GetAlertChannels(c *fiber.Ctx) error {
+	db := database.DB
+	var channels []models.AlertChannel
+	if err := db.Order("name ASC").Find(&channels).Error; err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	for i := range channels {
+		maskChannelSecrets(&channels[i])
+	}
+	return c.Status(200).JSON(channels)
+}
+
+func GetAlertChannel(c *fiber.Ctx) error {
+	db := database.DB
+	var channel models.AlertChannel
+	if err := db.First(&channel, c.Params("id")).Error; err != nil {
+		return c.Status(404).JSON(fiber.Map{
+			"error": "Alert channel not found",
+		})
+	}
+	maskChannelSecrets(&channel)
+	return c.Status(200).JSON(channel)
+}
+
+func CreateAlertChannel(c *fiber.Ctx) error {
+	db := database.DB
+
+	channel := new(models.AlertChannel)
+	if err := c.BodyParser(channel); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	channel.ID = 0
+	channel.ManagedBy = ""
+	if errs := validateAlertChannel(channel); len(errs) > 0 {
+		return c.Status(400).JSON(fiber.Map{
+			"error":  "Validation failed",
+			"fields": errs,
+		})
+	}
+
+	var count int64
+	db.Unscoped().Model(&models.AlertChannel{}).Where("name = ?", channel.Name).Count(&count)
+	if count > 0 {
+		return c.Status(409).JSON(fiber.Map{
+			"error": "An alert channel with this name already exists",
+		})
+	}
+
+	if err := db.Create(channel).Error; err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	maskChannelSecrets(channel)
+	return c.Status(200).JSON(channel)
+}
+
+// UpdateAlertChannel replaces the channel settings; masked secrets keep their stored value
+func UpdateAlertChannel(c *fiber.Ctx) error {
+	db := database.DB
+
+	var channel models.AlertChannel
+	if err := db.First(&channel, c.Params("id")).Error; err != nil {
+		return c.Status(404).JSON(fiber.Map{
+			"error": "Alert channel not found",
+		})
+	}
+	if channel.ManagedBy != "" {
+		return rejectManaged(c, channel.ManagedBy, "Alert channel")
+	}
+
+	var update models.AlertChannel
+	if err := c.BodyParser(&update); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	stored := channelConfigs(&channel)
+	for i, value := range channelConfigs(&update) {
+		if strings.HasPrefix(*value, secretMask) {
+			*value = *stored[i]
+		}
+	}
+	if errs := validateAlertChannel(&update); len(errs) > 0 {
+		return c.Status(400).JSON(fiber.Map{
+			"error":  "Validation failed",
+			"fields": errs,
+		})
+	}
+
+	if update.Name != channel.Name {
+		if refs := jobs.ChannelReferences(db, channel.Name); refs > 0 {
+			return c.Status(409).JSON(fiber.Map{
+				"error":      "Cannot rename a channel that is still referenced",
+				"references": refs,
+			})
+		}
+	}
+
+	channel.Name = update.Name
+	channel.Provider = update.Provider
+	channel.Config1 = update.Config1
+	channel.Config2 = update.Config2
+	channel.Config3 = update.Config3
+	channel.Config4 = update.Config4
+	channel.GroupBy = update.GroupBy
+	channel.GroupWindow = update.GroupWindow
+	if err := db.Save(&channel).Error; err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	maskChannelSecrets(&channel)
+	return c.Status(200).JSON(channel)
+}
+
+// DeleteAlertChannel refuses to delete a referenced channel unless ?reassign=<channel name> is given
+func DeleteAlertChannel(c *fiber.Ctx) error {
+	db := database.DB
+
+	var channel models.AlertChannel
+	if err := db.First(&channel, c.Params("id")).Error; err != nil {
+		return c.Status(404).JSON(fiber.Map{
+			"error": "Alert channel not found",
+		})
+	}
+	if channel.ManagedBy != "" {
+		return rejectManaged(c, channel.ManagedBy, "Alert channel")
+	}
+
+	reassign := c.Query("reassign")
+	refs := jobs.ChannelReferences(db, channel.Name)
+	if refs > 0 && reassign == "" {
+		return c.Status(409).JSON(fiber.Map{
+			"error":      "Alert channel is still referenced by hosts, escalation steps, alert routes, quiet hours or reports; pass ?reassign=<channel> to move them",
+			"references": refs,
+		})
+	}
+	if reassign != "" {
+		var target models.AlertChannel
+		if reassign == channel.Name || db.Where("name = ?", reassign).First(&target).Error != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "reassign must name another existing alert channel",
+			})
+		}
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if reassign != "" {
+			if err := jobs.ReassignChannel(tx, channel.Name, reassign); err != nil {
+				return err
+			}
+		}
+		// Hard delete so the unique name can be reused
+		return tx.Unscoped().Delete(&channel).Error
+	})
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	return c.Status(200).JSON(fiber.Map{
+		"message":    "Alert channel deleted",
+		"reassigned": refs,
+	})
+}
+
+func validateAlertChannel(channel *models.AlertChannel) map[string]string {
+	errs := map[string]string{}
+	channel.Name = strings.TrimSpace(channel.Name)
+	if channel.Name == "" {
+		errs["name"] = "name is required"
+	}
+	if channel.Provider == "" {
+		channel.Provider = channel.Name
+	}
+	if !jobs.IsKnownProvider(channel.Provider) {
+		errs["provider"] = "unknown provider " + channel.Provider
+	} else if spec, ok := channelConfigSpec[channel.Provider]; ok {
+		configs := channelConfigs(channel)
+		for _, field := range spec.required {
+			if strings.TrimSpace(*configs[field-1]) == "" {
+				errs[configFieldName(field)] = configFieldName(field) + " is required for " + channel.Provider
+			}
+		}
+	}
+	switch channel.GroupBy {
+	case "":
+		channel.GroupBy = "none"
+	case "none", "channel", "device_type", "parent", "group":
+	default:
+		if !strings.HasPrefix(channel.GroupBy, "tag:") || channel.GroupBy == "tag:" {
+			errs["group_by"] = "group_by must be none, channel, device_type, parent, group or tag:<key>"
+		}
+	}
+	if channel.GroupWindow < 0 {
+		errs["group_window"] = "group_window cannot be negative"
+	}
+	return errs
+}
+
+// maskChannelSecrets hides secret config values, keeping the last four characters as a hint
+func maskChannelSecrets(channel *models.AlertChannel) {
+	spec, ok := channelConfigSpec[channel.Provider]
+	if !ok {
+		spec = channelConfigSpec[channel.Name]
+	}
+	configs := channelConfigs(channel)
+	for _, field := range spec.secrets {
+		value := configs[field-1]
+		if *value == "" {
+			continue
+		}
+		if len(*value) > 8 {
+			*value = secretMask + (*value)[len(*value)-4:]
+		} else {
+			*value = secretMask
+		}
+	}
+}
+
+func channelConfigs(channel *models.AlertChannel) []*string {
+	return []*string{&channel.Config1, &channel.Config2, &channel.Config3, &channel.Config4}
+}
+
+func configFieldName(field int) string {
+	return "config" + string(rune('0'+field))
+}
+
+// TestAlertChannel sends a sample message and returns the provider's answer synchronously
+func TestAlertChannel(c *fiber.Ctx) error {
+	db := database.DB
+
+	var channel models.AlertChannel
+	if err := db.First(&channel, c.Params("id")).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Alert channel not found",
+		})
+	}
+
+	var request struct {
+		Recipient string `json:"recipient"`
+	}
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&request); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+	}
+
+	notification, err := jobs.SendTestNotification(&channel, request.Recipient)
+	if notification == nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusBadGateway).JSON(fiber.Map{
+			"success":         false,
+			"error":           err.Error(),
+			"response":        notification.Response,
+			"notification_id": notification.ID,
+		})
+	}
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"success":         true,
+		"response":        notification.Response,
+		"notification_id": notification.ID,
+	})
+}
@@ -0,0 +1,53 @@
+package handlers
+
+import (
+	"alerting-app/database"
+	"alerting-app/inventory"
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// ReloadConfig syncs the database to CONFIG_FILE and returns the diff.
+// ?dry_run=true only reports the diff; ?prune=true|false overrides CONFIG_PRUNE.
+func ReloadConfig(c *fiber.Ctx) error {
+	path := inventory.FilePath()
+	if path == "" {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "CONFIG_FILE is not set",
+		})
+	}
+
+	result, err := inventory.Sync(database.DB, path, c.QueryBool("prune", inventory.PruneByDefault()), c.QueryBool("dry_run"))
+	if errors.Is(err, inventory.ErrInvalid) {
+		return c.Status(400).JSON(fiber.Map{
+			"error":  err.Error(),
+			"errors": result.Errors,
+		})
+	}
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	return c.Status(200).JSON(result)
+}
+
+// GetConfigSync returns the result of the last applied sync
+func GetConfigSync(c *fiber.Ctx) error {
+	result := inventory.LastSync()
+	if result == nil {
+		return c.Status(404).JSON(fiber.Map{
+			"error": "No configuration sync has run",
+		})
+	}
+	return c.Status(200).JSON(result)
+}
+
+// rejectManaged answers 403 for writes to an object owned by the configuration file
+func rejectManaged(c *fiber.Ctx, managedBy, what string) error {
+	return c.Status(403).JSON(fiber.Map{
+		"error":      what + " is managed by the configuration file and is read-only",
+		"managed_by": managedBy,
+	})
+}
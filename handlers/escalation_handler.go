@@ -0,0 +1,198 @@
+package handlers
+
+import (
+	"alerting-app/database"
+	"alerting-app/models"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+func GetEscalationPolicies(c *fiber.Ctx) error {
+	db := database.DB
+	var policies []models.EscalationPolicy
+	if err := db.Preload("Steps", func(db *gorm.DB) *gorm.DB {
+		return db.Order("position ASC")
+	}).Find(&policies).Error; err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	return c.Status(200).JSON(policies)
+}
+
+func CreateEscalationPolicy(c *fiber.Ctx) error {
+	db := database.DB
+
+	policy := new(models.EscalationPolicy)
+	if err := c.BodyParser(policy); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	if msg := validateEscalationPolicy(policy); msg != "" {
+		return c.Status(400).JSON(fiber.Map{
+			"error": msg,
+		})
+	}
+
+	if err := db.Create(policy).Error; err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	return c.Status(200).JSON(policy)
+}
+
+// UpdateEscalationPolicy replaces the policy settings and its whole step list
+func UpdateEscalationPolicy(c *fiber.Ctx) error {
+	db := database.DB
+
+	var policy models.EscalationPolicy
+	if err := db.First(&policy, c.Params("id")).Error; err != nil {
+		return c.Status(404).JSON(fiber.Map{
+			"error": "Escalation policy not found",
+		})
+	}
+
+	var update models.EscalationPolicy
+	if err := c.BodyParser(&update); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	if msg := validateEscalationPolicy(&update); msg != "" {
+		return c.Status(400).JSON(fiber.Map{
+			"error": msg,
+		})
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		policy.Name = update.Name
+		policy.RepeatWhileDown = update.RepeatWhileDown
+		policy.RepeatInterval = update.RepeatInterval
+		if err := tx.Save(&policy).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("policy_id = ?", policy.ID).Delete(&models.EscalationStep{}).Error; err != nil {
+			return err
+		}
+		for i := range update.Steps {
+			update.Steps[i].ID = 0
+			update.Steps[i].PolicyID = policy.ID
+		}
+		if len(update.Steps) > 0 {
+			if err := tx.Create(&update.Steps).Error; err != nil {
+				return err
+			}
+		}
+		policy.Steps = update.Steps
+		return nil
+	})
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	return c.Status(200).JSON(policy)
+}
+
+func DeleteEscalationPolicy(c *fiber.Ctx) error {
+	db := database.DB
+
+	var policy models.EscalationPolicy
+	if err := db.First(&policy, c.Params("id")).Error; err != nil {
+		return c.Status(404).JSON(fiber.Map{
+			"error": "Escalation policy not found",
+		})
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Host{}).Where("escalation_policy_id = ?", policy.ID).
+			Updates(map[string]interface{}{"escalation_policy_id": nil, "version": models.NextHostVersion}).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.DeviceType{}).Where("escalation_policy_id = ?", policy.ID).Update("escalation_policy_id", nil).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("policy_id = ?", policy.ID).Delete(&models.EscalationStep{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&policy).Error
+	})
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	return c.Status(200).JSON(fiber.Map{
+		"message": "Escalation policy deleted",
+	})
+}
+
+// AttachEscalationPolicy assigns the policy to the given hosts and device types
+func AttachEscalationPolicy(c *fiber.Ctx) error {
+	db := database.DB
+
+	var policy models.EscalationPolicy
+	if err := db.First(&policy, c.Params("id")).Error; err != nil {
+		return c.Status(404).JSON(fiber.Map{
+			"error": "Escalation policy not found",
+		})
+	}
+
+	var request struct {
+		HostIDs     []uint   `json:"host_ids"`
+		DeviceTypes []string `json:"device_types"`
+	}
+	if err := c.BodyParser(&request); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	// Hosts and device types owned by the configuration file keep the policy set there
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if len(request.HostIDs) > 0 {
+			if err := tx.Model(&models.Host{}).Where("id IN ? AND managed_by = ''", request.HostIDs).
+				Updates(map[string]interface{}{"escalation_policy_id": policy.ID, "version": models.NextHostVersion}).Error; err != nil {
+				return err
+			}
+		}
+		if len(request.DeviceTypes) > 0 {
+			if err := tx.Model(&models.DeviceType{}).Where("dev_type IN ? AND managed_by = ''", request.DeviceTypes).Update("escalation_policy_id", policy.ID).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	return c.Status(200).JSON(fiber.Map{
+		"message": "Escalation policy attached",
+	})
+}
+
+func validateEscalationPolicy(policy *models.EscalationPolicy) string {
+	if policy.Name == "" {
+		return "name is required"
+	}
+	if policy.RepeatWhileDown && policy.RepeatInterval <= 0 {
+		return "repeat_interval must be positive when repeat_while_down is set"
+	}
+	for i, step := range policy.Steps {
+		if step.ChannelName == "" && step.UserID == nil && step.ScheduleID == nil {
+			return "every step needs a channel_name, user_id or schedule_id"
+		}
+		if step.DelayMinutes < 0 {
+			return "step delay_minutes cannot be negative"
+		}
+		if step.Position == 0 {
+			policy.Steps[i].Position = i + 1
+		}
+	}
+	return ""
+}
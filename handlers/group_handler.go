@@ -0,0 +1,446 @@
+package handlers
+
+import (
+	"alerting-app/database"
+	"alerting-app/jobs"
+	"alerting-app/models"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+func GetHostGroups(c *fiber.Ctx) error {
+	db := database.DB
+	var groups []models.HostGroup
+	if err := db.Order("name ASC").Find(&groups).Error; err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	return c.Status(200).JSON(groups)
+}
+
+func CreateHostGroup(c *fiber.Ctx) error {
+	db := database.DB
+
+	group := new(models.HostGroup)
+	if err := c.BodyParser(group); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	group.ID = 0
+	if msg := validateHostGroup(group); msg != "" {
+		return c.Status(400).JSON(fiber.Map{
+			"error": msg,
+		})
+	}
+
+	if err := db.Create(group).Error; err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	return c.Status(200).JSON(group)
+}
+
+func UpdateHostGroup(c *fiber.Ctx) error {
+	db := database.DB
+
+	var group models.HostGroup
+	if err := db.First(&group, c.Params("id")).Error; err != nil {
+		return c.Status(404).JSON(fiber.Map{
+			"error": "Host group not found",
+		})
+	}
+
+	var update models.HostGroup
+	if err := c.BodyParser(&update); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	update.ID = group.ID
+	if msg := validateHostGroup(&update); msg != "" {
+		return c.Status(400).JSON(fiber.Map{
+			"error": msg,
+		})
+	}
+
+	group.Name = update.Name
+	group.ParentID = update.ParentID
+	group.Description = update.Description
+	if err := db.Save(&group).Error; err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	return c.Status(200).JSON(group)
+}
+
+// groupReferrers are the selectors scoped to a group besides hosts and subgroups
+var groupReferrers = []interface{}{&models.AlertRoute{}, &models.MaintenanceWindow{}, &models.ReportDefinition{}, &models.StatusPageComponent{}}
+
+// DeleteHostGroup removes the group; its subgroups, hosts, alert routes, maintenance windows,
+// report definitions and status page components move up to its parent. A top-level group still
+// used by any of those is refused, since clearing their group would widen them to every host.
+func DeleteHostGroup(c *fiber.Ctx) error {
+	db := database.DB
+
+	var group models.HostGroup
+	if err := db.First(&group, c.Params("id")).Error; err != nil {
+		return c.Status(404).JSON(fiber.Map{
+			"error": "Host group not found",
+		})
+	}
+	if group.ParentID == nil {
+		var refs int64
+		for _, model := range groupReferrers {
+			var count int64
+			db.Model(model).Where("group_id = ?", group.ID).Count(&count)
+			refs += count
+		}
+		if refs > 0 {
+			return c.Status(409).JSON(fiber.Map{
+				"error":      "Host group is still used by alert routes, maintenance windows, report definitions or status pages",
+				"references": refs,
+			})
+		}
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.HostGroup{}).Where("parent_id = ?", group.ID).Update("parent_id", group.ParentID).Error; err != nil {
+			return err
+		}
+		// Deleted hosts move too, so restoring one does not point at a missing group
+		if err := tx.Unscoped().Model(&models.Host{}).Where("group_id = ?", group.ID).
+			Updates(map[string]interface{}{"group_id": group.ParentID, "version": models.NextHostVersion}).Error; err != nil {
+			return err
+		}
+		for _, model := range groupReferrers {
+			if err := tx.Unscoped().Model(model).Where("group_id = ?", group.ID).Update("group_id", group.ParentID).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Unscoped().Delete(&group).Error
+	})
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	return c.Status(200).JSON(fiber.Map{
+		"message": "Host group deleted",
+	})
+}
+
+// GetHostGroupStatus rolls up the state of the hosts in the group and its subgroups
+func GetHostGroupStatus(c *fiber.Ctx) error {
+	db := database.DB
+
+	var group models.HostGroup
+	if err := db.First(&group, c.Params("id")).Error; err != nil {
+		return c.Status(404).JSON(fiber.Map{
+			"error": "Host group not found",
+		})
+	}
+	status, err := jobs.BuildGroupStatus(db, &group)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	return c.Status(200).JSON(status)
+}
+
+// GetHostGroupStatuses returns the rollup of every group
+func GetHostGroupStatuses(c *fiber.Ctx) error {
+	db := database.DB
+
+	var groups []models.HostGroup
+	if err := db.Order("name ASC").Find(&groups).Error; err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	statuses := make([]*jobs.GroupStatus, 0, len(groups))
+	for i := range groups {
+		status, err := jobs.BuildGroupStatus(db, &groups[i])
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		statuses = append(statuses, status)
+	}
+	return c.Status(200).JSON(statuses)
+}
+
+// bulkGroupRequest is one bulk operation on the hosts of a group
+type bulkGroupRequest struct {
+	Action           string `json:"action"` // "pause", "resume", "set_interval" or "set_channel"
+	Interval         int    `json:"interval"`
+	AlertChannelName string `json:"alert_channel_name"`
+	IncludeSubgroups *bool  `json:"include_subgroups"` // defaults to true
+}
+
+// BulkUpdateHostGroup applies one change to every host of the group
+func BulkUpdateHostGroup(c *fiber.Ctx) error {
+	db := database.DB
+
+	var group models.HostGroup
+	if err := db.First(&group, c.Params("id")).Error; err != nil {
+		return c.Status(404).JSON(fiber.Map{
+			"error": "Host group not found",
+		})
+	}
+
+	var req bulkGroupRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	var column string
+	var value interface{}
+	switch req.Action {
+	case "pause":
+		column, value = "is_active", false
+	case "resume":
+		column, value = "is_active", true
+	case "set_interval":
+		if req.Interval < 1 {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "interval must be at least 1 minute",
+			})
+		}
+		column, value = "interval", req.Interval
+	case "set_channel":
+		var channel models.AlertChannel
+		if err := db.Where("name = ?", req.AlertChannelName).First(&channel).Error; err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "alert_channel_name does not exist",
+			})
+		}
+		column, value = "alert_channel_name", req.AlertChannelName
+	default:
+		return c.Status(400).JSON(fiber.Map{
+			"error": "action must be pause, resume, set_interval or set_channel",
+		})
+	}
+
+	// Hosts owned by the configuration file are left alone
+	hosts := db.Model(&models.Host{}).Where("managed_by = ''").Session(&gorm.Session{})
+	query := hosts.Where("group_id = ?", group.ID)
+	if req.IncludeSubgroups == nil || *req.IncludeSubgroups {
+		var err error
+		if query, err = jobs.WhereGroup(db, hosts, group.ID); err != nil {
+			return c.Status(500).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+	}
+	result := query.Updates(map[string]interface{}{column: value, "version": models.NextHostVersion})
+	if result.Error != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": result.Error.Error(),
+		})
+	}
+	return c.Status(200).JSON(fiber.Map{
+		"message": "Hosts updated",
+		"updated": result.RowsAffected,
+	})
+}
+
+// SetHostTags replaces the tags of a host with the key/value object in the body
+func SetHostTags(c *fiber.Ctx) error {
+	db := database.DB
+
+	var host models.Host
+	if err := db.First(&host, c.Params("id")).Error; err != nil {
+		return c.Status(404).JSON(fiber.Map{
+			"error": "Host not found",
+		})
+	}
+	if host.ManagedBy != "" {
+		return rejectManaged(c, host.ManagedBy, "Host")
+	}
+
+	var body map[string]string
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	tags := make([]models.HostTag, 0, len(body))
+	for key, value := range body {
+		key = strings.TrimSpace(key)
+		if key == "" || strings.ContainsAny(key, "=,") || strings.Contains(value, ",") {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "tag keys cannot be empty or contain '=' or ',', values cannot contain ','",
+			})
+		}
+		tags = append(tags, models.HostTag{HostID: host.ID, Key: key, Value: strings.TrimSpace(value)})
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&host).UpdateColumn("version", models.NextHostVersion).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("host_id = ?", host.ID).Delete(&models.HostTag{}).Error; err != nil {
+			return err
+		}
+		if len(tags) == 0 {
+			return nil
+		}
+		return tx.Create(&tags).Error
+	})
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	return c.Status(200).JSON(tags)
+}
+
+func GetAlertRoutes(c *fiber.Ctx) error {
+	db := database.DB
+	var routes []models.AlertRoute
+	if err := db.Order("name ASC").Find(&routes).Error; err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	return c.Status(200).JSON(routes)
+}
+
+func CreateAlertRoute(c *fiber.Ctx) error {
+	db := database.DB
+
+	route := new(models.AlertRoute)
+	if err := c.BodyParser(route); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	route.ID = 0
+	if msg := validateAlertRoute(route); msg != "" {
+		return c.Status(400).JSON(fiber.Map{
+			"error": msg,
+		})
+	}
+	if err := db.Create(route).Error; err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	return c.Status(200).JSON(route)
+}
+
+func UpdateAlertRoute(c *fiber.Ctx) error {
+	db := database.DB
+
+	var route models.AlertRoute
+	if err := db.First(&route, c.Params("id")).Error; err != nil {
+		return c.Status(404).JSON(fiber.Map{
+			"error": "Alert route not found",
+		})
+	}
+
+	var update models.AlertRoute
+	if err := c.BodyParser(&update); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	if msg := validateAlertRoute(&update); msg != "" {
+		return c.Status(400).JSON(fiber.Map{
+			"error": msg,
+		})
+	}
+
+	route.Name = update.Name
+	route.TagSelector = update.TagSelector
+	route.GroupID = update.GroupID
+	route.ChannelName = update.ChannelName
+	if err := db.Save(&route).Error; err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	return c.Status(200).JSON(route)
+}
+
+func DeleteAlertRoute(c *fiber.Ctx) error {
+	db := database.DB
+
+	var route models.AlertRoute
+	if err := db.First(&route, c.Params("id")).Error; err != nil {
+		return c.Status(404).JSON(fiber.Map{
+			"error": "Alert route not found",
+		})
+	}
+	if err := db.Delete(&route).Error; err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	return c.Status(200).JSON(fiber.Map{
+		"message": "Alert route deleted",
+	})
+}
+
+func validateHostGroup(group *models.HostGroup) string {
+	db := database.DB
+	group.Name = strings.TrimSpace(group.Name)
+	if group.Name == "" {
+		return "name is required"
+	}
+	if group.ParentID == nil {
+		return ""
+	}
+	var parent models.HostGroup
+	if err := db.First(&parent, *group.ParentID).Error; err != nil {
+		return "parent_id does not exist"
+	}
+	if group.ID != 0 {
+		// The new parent cannot be the group itself or one of its subgroups
+		below, err := jobs.GroupAndDescendants(db, group.ID)
+		if err != nil {
+			return err.Error()
+		}
+		for _, id := range below {
+			if id == *group.ParentID {
+				return "a group cannot be moved below itself"
+			}
+		}
+	}
+	return ""
+}
+
+func validateAlertRoute(route *models.AlertRoute) string {
+	if route.GroupID == nil && strings.TrimSpace(route.TagSelector) == "" {
+		return "set group_id, tag_selector or both"
+	}
+	var channel models.AlertChannel
+	if err := database.DB.Where("name = ?", route.ChannelName).First(&channel).Error; err != nil {
+		return "channel_name does not exist"
+	}
+	return validateHostSelectors(route.GroupID, route.TagSelector)
+}
+
+// validateHostSelectors checks the optional group and tag selectors shared by routes, windows and reports
+func validateHostSelectors(groupID *uint, tagSelector string) string {
+	if groupID != nil {
+		var group models.HostGroup
+		if err := database.DB.First(&group, *groupID).Error; err != nil {
+			return "group_id does not exist"
+		}
+	}
+	if _, err := jobs.ParseTagSelector(tagSelector); err != nil {
+		return err.Error()
+	}
+	return ""
+}
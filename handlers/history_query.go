@@ -0,0 +1,183 @@
+package handlers
+
+import (
+	"alerting-app/models"
+	"bufio"
+	"encoding/base64"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+var historySortColumns = map[string]string{
+	"checked_at":    "checked_at",
+	"host_name":     "host_name",
+	"down_duration": "down_duration",
+}
+
+// historyQuery applies the GetHistory filters to query
+func historyQuery(c *fiber.Ctx, query *gorm.DB) (*gorm.DB, error) {
+	if ids := c.Query("host_id"); ids != "" {
+		var hostIDs []uint64
+		for _, id := range strings.Split(ids, ",") {
+			parsed, err := strconv.ParseUint(strings.TrimSpace(id), 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid host_id %q", id)
+			}
+			hostIDs = append(hostIDs, parsed)
+		}
+		query = query.Where("host_id IN ?", hostIDs)
+	}
+	if q := strings.TrimSpace(c.Query("q")); q != "" {
+		query = query.Where("host_name LIKE ?", "%"+escapeLike(q)+"%")
+	}
+	if deviceType := c.Query("device_type"); deviceType != "" {
+		query = query.Where("device_type = ?", deviceType)
+	}
+	if status := c.Query("status"); status != "" {
+		if status != "up" && status != "down" {
+			return nil, fmt.Errorf("status must be up or down")
+		}
+		query = query.Where("status = ?", status)
+	}
+	if alert := c.Query("alert_status"); alert != "" {
+		flag, err := strconv.ParseBool(alert)
+		if err != nil {
+			return nil, fmt.Errorf("invalid alert_status %q", alert)
+		}
+		query = query.Where("alert_status = ?", flag)
+	}
+	if value := c.Query("from"); value != "" {
+		from, err := parseTimeParam(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid from: %v", err)
+		}
+		query = query.Where("checked_at >= ?", from)
+	}
+	if value := c.Query("to"); value != "" {
+		to, err := parseTimeParam(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid to: %v", err)
+		}
+		query = query.Where("checked_at < ?", to)
+	}
+	return query, nil
+}
+
+func escapeLike(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(value)
+}
+
+// historyCursor is the sort key of the last row of a page
+type historyCursor struct {
+	Sort  string `json:"s"`
+	Value string `json:"v"`
+	ID    uint   `json:"id"`
+}
+
+func encodeHistoryCursor(row models.HostHistory, sort string) string {
+	cursor := historyCursor{Sort: sort, ID: row.ID}
+	switch sort {
+	case "host_name":
+		cursor.Value = row.HostName
+	case "down_duration":
+		cursor.Value = strconv.FormatFloat(row.DownDuration, 'g', -1, 64)
+	default:
+		cursor.Value = row.CheckedAt.Format(time.RFC3339Nano)
+	}
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// applyHistoryCursor continues after the row the cursor points at
+func applyHistoryCursor(query *gorm.DB, encoded, column string, desc bool) (*gorm.DB, error) {
+	data, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cursor")
+	}
+	var cursor historyCursor
+	if err := json.Unmarshal(data, &cursor); err != nil || historySortColumns[cursor.Sort] != column {
+		return nil, fmt.Errorf("invalid cursor for this sort")
+	}
+
+	var value interface{} = cursor.Value
+	switch cursor.Sort {
+	case "checked_at":
+		if value, err = time.Parse(time.RFC3339Nano, cursor.Value); err != nil {
+			return nil, fmt.Errorf("invalid cursor")
+		}
+	case "down_duration":
+		if value, err = strconv.ParseFloat(cursor.Value, 64); err != nil {
+			return nil, fmt.Errorf("invalid cursor")
+		}
+	}
+
+	op := ">"
+	if desc {
+		op = "<"
+	}
+	return query.Where(fmt.Sprintf("(%s %s ?) OR (%s = ? AND id %s ?)", column, op, column, op), value, value, cursor.ID), nil
+}
+
+var historyCSVHeader = []string{"id", "host_id", "host_name", "status", "checked_at", "device_type", "alert_status", "down_duration"}
+
+// exportHistory streams every row of the query without loading the table into memory
+func exportHistory(c *fiber.Ctx, query *gorm.DB, format string) error {
+	filename := "host-history-" + time.Now().Format("20060102-150405")
+	if format == "csv" {
+		c.Set(fiber.HeaderContentType, "text/csv; charset=utf-8")
+		filename += ".csv"
+	} else {
+		c.Set(fiber.HeaderContentType, "application/x-ndjson")
+		filename += ".ndjson"
+	}
+	c.Set(fiber.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="%s"`, filename))
+
+	rows, err := query.Rows()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Error fetching host history",
+		})
+	}
+
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		defer rows.Close()
+		var writer *csv.Writer
+		encoder := json.NewEncoder(w)
+		if format == "csv" {
+			writer = csv.NewWriter(w)
+			writer.Write(historyCSVHeader)
+		}
+		for rows.Next() {
+			var row models.HostHistory
+			if err := query.ScanRows(rows, &row); err != nil {
+				break
+			}
+			if writer != nil {
+				writer.Write([]string{
+					strconv.FormatUint(uint64(row.ID), 10),
+					strconv.FormatUint(uint64(row.HostID), 10),
+					row.HostName,
+					row.Status,
+					row.CheckedAt.Format(time.RFC3339),
+					row.DeviceType,
+					strconv.FormatBool(row.AlertStatus),
+					strconv.FormatFloat(row.DownDuration, 'f', -1, 64),
+				})
+			} else {
+				encoder.Encode(row)
+			}
+		}
+		if writer != nil {
+			writer.Flush()
+		}
+		w.Flush()
+	})
+	return nil
+}
@@ -3,8 +3,10 @@ package handlers
 import (
 	"alerting-app/database"
 	"alerting-app/models"
+	"errors"
 
 	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
 )
 
 func CreateHost(c *fiber.Ctx) error {
@@ -16,6 +18,14 @@ func CreateHost(c *fiber.Ctx) error {
 			"error": err.Error(),
 		})
 	}
+	host.ID = 0
+	host.ManagedBy = ""
+	if errs := validateHost(db, host); len(errs) > 0 {
+		return c.Status(400).JSON(fiber.Map{
+			"error":  "Validation failed",
+			"fields": errs,
+		})
+	}
 
 	if result := db.Create(&host); result.Error != nil {
 		return c.Status(500).JSON(fiber.Map{
@@ -26,20 +36,64 @@ func CreateHost(c *fiber.Ctx) error {
 	return c.Status(200).JSON(host)
 }
 
+// GetHosts lists hosts; see filterHosts for the filters.
+// Sorting: ?sort=<hostSortColumns key>&order=asc|desc
+// Paging: ?page=&limit= returns {data, page, limit, total, totalPages}; without either the plain array is returned
 func GetHosts(c *fiber.Ctx) error {
 	db := database.DB
 
 	var hosts []models.Host
 
-	// Ensure soft-deleted records are included
-	if result := db.Where("deleted_at IS NULL").Find(&hosts); result.Error != nil {
+	query, err := filterHosts(c, db, db.Model(&models.Host{}))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	ordered, err := sortHosts(c, query.Session(&gorm.Session{}))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	_, paged := c.Queries()["page"]
+	if _, limited := c.Queries()["limit"]; !paged && !limited {
+		if result := ordered.Preload("Tags").Find(&hosts); result.Error != nil {
+			return c.Status(500).JSON(fiber.Map{
+				"error": result.Error.Error(),
+			})
+		}
+		return c.JSON(hosts)
+	}
+
+	limit := c.QueryInt("limit", 50)
+	if limit < 1 || limit > 1000 {
+		limit = 50
+	}
+	page := c.QueryInt("page", 1)
+	if page < 1 {
+		page = 1
+	}
+	if result := ordered.Preload("Tags").Limit(limit).Offset((page - 1) * limit).Find(&hosts); result.Error != nil {
 		return c.Status(500).JSON(fiber.Map{
 			"error": result.Error.Error(),
 		})
 	}
 
-	// Return deleted hosts
-	return c.JSON(hosts)
+	var total int64
+	if err := query.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	return c.Status(200).JSON(fiber.Map{
+		"data":       hosts,
+		"page":       page,
+		"limit":      limit,
+		"total":      total,
+		"totalPages": (total + int64(limit) - 1) / int64(limit),
+	})
 }
 
 func GetMethod(c *fiber.Ctx) error {
@@ -75,6 +129,11 @@ func GetAlert(c *fiber.Ctx) error {
 	return c.Status(200).JSON(response)
 }
 
+// hostPutColumns are the columns a PUT replaces; retry_count stays with the scheduler
+var hostPutColumns = []string{"name", "ip", "method_id", "interval", "num_of_retry", "is_active", "expected_response",
+	"device_type_name", "escalation_policy_id", "parent_id", "severity", "sla_target", "group_id"}
+
+// UpdateHost replaces the editable fields of a host; If-Match with the ETag fails with 409 when it was edited since
 func UpdateHost(c *fiber.Ctx) error {
 	db := database.DB
 
@@ -89,6 +148,12 @@ func UpdateHost(c *fiber.Ctx) error {
 			"error": "Host not found",
 		})
 	}
+	if host.ManagedBy != "" {
+		return rejectManaged(c, host.ManagedBy, "Host")
+	}
+	if match := c.Get(fiber.HeaderIfMatch); match != "" && match != "*" && match != hostETag(&host) {
+		return hostConflict(c, &host)
+	}
 
 	// Parse request body
 	if err := c.BodyParser(&updateHost); err != nil {
@@ -100,19 +165,42 @@ func UpdateHost(c *fiber.Ctx) error {
 	host.IP = updateHost.IP
 	host.MethodID = updateHost.MethodID
 	host.Interval = updateHost.Interval
-	host.RetryCount = updateHost.RetryCount
 	host.NumOfRetry = updateHost.NumOfRetry
 	host.IsActive = updateHost.IsActive
 	host.ExpectedResponse = updateHost.ExpectedResponse
 	host.DeviceTypeName = updateHost.DevType
+	host.EscalationPolicyID = updateHost.EscalationPolicyID
+	host.ParentID = updateHost.ParentID
+	if updateHost.Severity != "" {
+		host.Severity = updateHost.Severity
+	}
+	host.SLATarget = updateHost.SLATarget
+	host.GroupID = updateHost.GroupID
+	if errs := validateHost(db, &host); len(errs) > 0 {
+		return c.Status(400).JSON(fiber.Map{
+			"error":  "Validation failed",
+			"fields": errs,
+		})
+	}
 	// host.DeviceTypeName = updateHost.DeviceType.DevType
 	// Update the existing host record
-	if err := db.Save(&host).Error; err != nil {
+	err := saveHostColumns(db, &host, host.Version, hostPutColumns)
+	if errors.Is(err, errHostConflict) {
+		db.Preload("Tags").First(&host, host.ID)
+		return hostConflict(c, &host)
+	}
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	if err := db.First(&host, host.ID).Error; err != nil {
 		return c.Status(500).JSON(fiber.Map{
 			"error": err.Error(),
 		})
 	}
 
+	c.Set(fiber.HeaderETag, hostETag(&host))
 	return c.Status(200).JSON(host)
 }
 
@@ -127,6 +215,9 @@ func DeleteHost(c *fiber.Ctx) error {
 			"error": "Host not found",
 		})
 	}
+	if host.ManagedBy != "" {
+		return rejectManaged(c, host.ManagedBy, "Host")
+	}
 	if err := db.Delete(&host).Error; err != nil {
 		return c.Status(500).JSON(fiber.Map{
 			"error": err.Error(),
@@ -137,25 +228,88 @@ func DeleteHost(c *fiber.Ctx) error {
 	})
 }
 
+// GetHistory lists host state changes.
+// Filters: ?host_id=1,2 ?q=name ?device_type= ?status=up|down ?alert_status=true|false ?from=&to=
+// Sorting: ?sort=checked_at|host_name|down_duration&order=asc|desc
+// Paging: ?page=&limit= or ?cursor= (empty cursor starts at the top); ?format=csv|ndjson exports every match
 func GetHistory(c *fiber.Ctx) error {
 	db := database.DB
-	var history []models.HostHistory
+
+	query, err := historyQuery(c, db.Model(&models.HostHistory{}))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	sort := c.Query("sort", "checked_at")
+	column, ok := historySortColumns[sort]
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "sort must be checked_at, host_name or down_duration",
+		})
+	}
+	desc := c.Query("order", "desc") != "asc"
+	direction := "ASC"
+	if desc {
+		direction = "DESC"
+	}
+	ordered := query.Session(&gorm.Session{}).Order(column + " " + direction).Order("id " + direction)
+
+	switch c.Query("format") {
+	case "csv", "ndjson":
+		return exportHistory(c, ordered, c.Query("format"))
+	}
 
 	// Get pagination parameters from query
-	page := c.QueryInt("page", 1)   // Default to page 1 if not provided
-	limit := c.QueryInt("limit", 5) // Default limit to 10 if not provided
-	offset := (page - 1) * limit    // Calculate offset
+	limit := c.QueryInt("limit", 5) // Default limit to 5 if not provided
+	if limit < 1 || limit > 1000 {
+		limit = 5
+	}
+
+	if _, cursorMode := c.Queries()["cursor"]; cursorMode {
+		var history []models.HostHistory
+		paged := ordered
+		if cursor := c.Query("cursor"); cursor != "" {
+			paged, err = applyHistoryCursor(ordered, cursor, column, desc)
+			if err != nil {
+				return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+					"error": err.Error(),
+				})
+			}
+		}
+		if err := paged.Limit(limit + 1).Find(&history).Error; err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Error fetching host history",
+			})
+		}
+		next := ""
+		if len(history) > limit {
+			history = history[:limit]
+			next = encodeHistoryCursor(history[limit-1], sort)
+		}
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{
+			"data":        history,
+			"limit":       limit,
+			"next_cursor": next,
+		})
+	}
+
+	var history []models.HostHistory
+	page := c.QueryInt("page", 1) // Default to page 1 if not provided
+	if page < 1 {
+		page = 1
+	}
+	offset := (page - 1) * limit // Calculate offset
 
-	// Fetch paginated history ordered by CheckedAt
-	if err := db.Order("checked_at DESC").Limit(limit).Offset(offset).Find(&history).Error; err != nil {
+	if err := ordered.Limit(limit).Offset(offset).Find(&history).Error; err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Error fetching host history",
 		})
 	}
 
-	// Count total records for pagination info
+	// Count the filtered records for pagination info
 	var total int64
-	db.Model(&models.HostHistory{}).Count(&total)
+	query.Session(&gorm.Session{}).Count(&total)
 
 	// Return paginated response
 	return c.Status(fiber.StatusOK).JSON(fiber.Map{
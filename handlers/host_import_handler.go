@@ -0,0 +1,607 @@
+package handlers
+
+import (
+	"alerting-app/database"
+	"alerting-app/models"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"gopkg.in/yaml.v3"
+	"gorm.io/gorm"
+)
+
+// hostRecord is the portable form of a host used by import and export.
+// Pointer and empty fields are left unchanged when updating an existing host.
+type hostRecord struct {
+	Name             string            `json:"name" yaml:"name"`
+	IP               string            `json:"ip" yaml:"ip"`
+	Method           string            `json:"method,omitempty" yaml:"method,omitempty"`
+	Interval         *int              `json:"interval,omitempty" yaml:"interval,omitempty"`
+	NumOfRetry       *int              `json:"num_of_retry,omitempty" yaml:"num_of_retry,omitempty"`
+	IsActive         *bool             `json:"is_active,omitempty" yaml:"is_active,omitempty"`
+	DeviceType       string            `json:"device_type,omitempty" yaml:"device_type,omitempty"`
+	AlertChannel     string            `json:"alert_channel,omitempty" yaml:"alert_channel,omitempty"`
+	ExpectedResponse *int              `json:"expected_response,omitempty" yaml:"expected_response,omitempty"`
+	HttpHeader       *string           `json:"http_header,omitempty" yaml:"http_header,omitempty"`
+	HttpBody         *string           `json:"http_body,omitempty" yaml:"http_body,omitempty"`
+	Severity         string            `json:"severity,omitempty" yaml:"severity,omitempty"`
+	SLATarget        *float64          `json:"sla_target,omitempty" yaml:"sla_target,omitempty"`
+	Group            string            `json:"group,omitempty" yaml:"group,omitempty"`
+	Tags             map[string]string `json:"tags,omitempty" yaml:"tags,omitempty"`
+
+	invalid map[string]string // CSV cells that could not be parsed, by column
+}
+
+var hostCSVHeader = []string{"name", "ip", "method", "interval", "num_of_retry", "is_active", "device_type",
+	"alert_channel", "expected_response", "http_header", "http_body", "severity", "sla_target", "group", "tags"}
+
+// importRow reports what happened to one input row
+type importRow struct {
+	Row    int               `json:"row"`
+	Name   string            `json:"name"`
+	Action string            `json:"action"` // "create", "update", "skip" or "error"
+	HostID uint              `json:"host_id,omitempty"`
+	Errors map[string]string `json:"errors,omitempty"`
+}
+
+// ImportHosts creates or updates hosts from a CSV, JSON or YAML document.
+// ?format=csv|json|yaml (default: from the upload name or content type), ?match=name|ip, ?dry_run=true.
+// The body is the document itself or a multipart upload in the "file" field.
+func ImportHosts(c *fiber.Ctx) error {
+	db := database.DB
+
+	data, filename, err := importBody(c)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	format := importFormat(c, filename)
+	match := c.Query("match", "name")
+	if match != "name" && match != "ip" {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "match must be name or ip",
+		})
+	}
+	dryRun := c.QueryBool("dry_run")
+
+	records, err := decodeHostRecords(data, format)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	lookups, err := loadImportLookups(db)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	summary := map[string]int{"create": 0, "update": 0, "skip": 0, "error": 0}
+	rows := make([]importRow, 0, len(records))
+	seen := map[string]int{}
+	for i, record := range records {
+		row := importRow{Row: i + 1, Name: record.Name}
+		key := strings.ToLower(strings.TrimSpace(record.Name))
+		if match == "ip" {
+			key = strings.TrimSpace(record.IP)
+		}
+
+		if errs := lookups.validate(&record); len(errs) > 0 {
+			row.Action, row.Errors = "error", errs
+		} else if first, dup := seen[key]; dup {
+			row.Action, row.Errors = "error", map[string]string{match: fmt.Sprintf("duplicate of row %d", first)}
+		} else {
+			seen[key] = row.Row
+			var errs map[string]string
+			row.Action, row.HostID, errs, err = importHostRecord(db, &record, match, lookups, dryRun)
+			if err != nil {
+				errs = map[string]string{"row": err.Error()}
+			}
+			if len(errs) > 0 {
+				row.Action, row.Errors = "error", errs
+			}
+		}
+		summary[row.Action]++
+		rows = append(rows, row)
+	}
+
+	return c.Status(200).JSON(fiber.Map{
+		"dry_run": dryRun,
+		"format":  format,
+		"created": summary["create"],
+		"updated": summary["update"],
+		"skipped": summary["skip"],
+		"errors":  summary["error"],
+		"rows":    rows,
+	})
+}
+
+// ExportHosts writes the hosts as CSV, JSON or YAML; it accepts the GetHosts filters
+func ExportHosts(c *fiber.Ctx) error {
+	db := database.DB
+
+	format := c.Query("format", "csv")
+	if format != "csv" && format != "json" && format != "yaml" {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "format must be csv, json or yaml",
+		})
+	}
+	query, err := filterHosts(c, db, db.Preload("Tags").Preload("Method"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	var hosts []models.Host
+	if err := query.Order("name ASC").Find(&hosts).Error; err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	var groups []models.HostGroup
+	db.Find(&groups)
+	groupNames := map[uint]string{}
+	for _, group := range groups {
+		groupNames[group.ID] = group.Name
+	}
+
+	records := make([]hostRecord, 0, len(hosts))
+	for i := range hosts {
+		records = append(records, exportHostRecord(&hosts[i], groupNames))
+	}
+
+	c.Set(fiber.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="hosts-%s.%s"`, time.Now().Format("20060102"), format))
+	switch format {
+	case "json":
+		return c.Status(200).JSON(records)
+	case "yaml":
+		out, err := yaml.Marshal(records)
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		c.Set(fiber.HeaderContentType, "application/yaml; charset=utf-8")
+		return c.Send(out)
+	default:
+		c.Set(fiber.HeaderContentType, "text/csv; charset=utf-8")
+		writer := csv.NewWriter(c)
+		writer.Write(hostCSVHeader)
+		for _, record := range records {
+			writer.Write(hostCSVRecord(record))
+		}
+		writer.Flush()
+		return writer.Error()
+	}
+}
+
+func importBody(c *fiber.Ctx) ([]byte, string, error) {
+	if file, err := c.FormFile("file"); err == nil {
+		f, err := file.Open()
+		if err != nil {
+			return nil, "", err
+		}
+		defer f.Close()
+		data, err := io.ReadAll(f)
+		return data, file.Filename, err
+	}
+	if len(c.Body()) == 0 {
+		return nil, "", errors.New("the request has no document, send it as the body or as the file field")
+	}
+	return c.Body(), "", nil
+}
+
+func importFormat(c *fiber.Ctx, filename string) string {
+	if format := c.Query("format"); format != "" {
+		return format
+	}
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".json":
+		return "json"
+	case ".yaml", ".yml":
+		return "yaml"
+	case ".csv":
+		return "csv"
+	}
+	contentType := string(c.Request().Header.ContentType())
+	switch {
+	case strings.Contains(contentType, "json"):
+		return "json"
+	case strings.Contains(contentType, "yaml"):
+		return "yaml"
+	}
+	return "csv"
+}
+
+func decodeHostRecords(data []byte, format string) ([]hostRecord, error) {
+	var records []hostRecord
+	switch format {
+	case "json":
+		if err := json.Unmarshal(data, &records); err != nil {
+			return nil, fmt.Errorf("invalid JSON: %v", err)
+		}
+	case "yaml":
+		if err := yaml.Unmarshal(data, &records); err != nil {
+			return nil, fmt.Errorf("invalid YAML: %v", err)
+		}
+	case "csv":
+		return decodeHostCSV(data)
+	default:
+		return nil, fmt.Errorf("format must be csv, json or yaml")
+	}
+	return records, nil
+}
+
+// decodeHostCSV reads a CSV with a header row; columns follow hostCSVHeader in any order
+func decodeHostCSV(data []byte) ([]hostRecord, error) {
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.TrimLeadingSpace = true
+	reader.FieldsPerRecord = -1
+	lines, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("invalid CSV: %v", err)
+	}
+	if len(lines) == 0 {
+		return nil, nil
+	}
+
+	columns := map[string]int{}
+	for i, name := range lines[0] {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	if _, ok := columns["name"]; !ok {
+		return nil, errors.New("the CSV header must contain a name column")
+	}
+
+	records := make([]hostRecord, 0, len(lines)-1)
+	for _, line := range lines[1:] {
+		get := func(column string) string {
+			if i, ok := columns[column]; ok && i < len(line) {
+				return strings.TrimSpace(line[i])
+			}
+			return ""
+		}
+		record := hostRecord{
+			Name:         get("name"),
+			IP:           get("ip"),
+			Method:       get("method"),
+			DeviceType:   get("device_type"),
+			AlertChannel: get("alert_channel"),
+			Severity:     get("severity"),
+			Group:        get("group"),
+		}
+		record.invalid = map[string]string{}
+		record.Interval = csvInt(get("interval"), "interval", record.invalid)
+		record.NumOfRetry = csvInt(get("num_of_retry"), "num_of_retry", record.invalid)
+		record.ExpectedResponse = csvInt(get("expected_response"), "expected_response", record.invalid)
+		if value := get("is_active"); value != "" {
+			if active, err := strconv.ParseBool(value); err == nil {
+				record.IsActive = &active
+			} else {
+				record.invalid["is_active"] = fmt.Sprintf("invalid boolean %q", value)
+			}
+		}
+		if value := get("sla_target"); value != "" {
+			if target, err := strconv.ParseFloat(value, 64); err == nil {
+				record.SLATarget = &target
+			} else {
+				record.invalid["sla_target"] = fmt.Sprintf("invalid number %q", value)
+			}
+		}
+		if value := get("http_header"); value != "" {
+			record.HttpHeader = &value
+		}
+		if value := get("http_body"); value != "" {
+			record.HttpBody = &value
+		}
+		if value := get("tags"); value != "" {
+			record.Tags = map[string]string{}
+			for _, term := range strings.Split(value, ",") {
+				key, tagValue, _ := strings.Cut(term, "=")
+				record.Tags[strings.TrimSpace(key)] = strings.TrimSpace(tagValue)
+			}
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+// csvInt parses an optional integer cell, recording a bad value in invalid
+func csvInt(value, column string, invalid map[string]string) *int {
+	if value == "" {
+		return nil
+	}
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		invalid[column] = fmt.Sprintf("invalid number %q", value)
+		return nil
+	}
+	return &parsed
+}
+
+func hostCSVRecord(record hostRecord) []string {
+	optInt := func(v *int) string {
+		if v == nil {
+			return ""
+		}
+		return strconv.Itoa(*v)
+	}
+	optString := func(v *string) string {
+		if v == nil {
+			return ""
+		}
+		return *v
+	}
+	keys := make([]string, 0, len(record.Tags))
+	for key := range record.Tags {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	tags := make([]string, 0, len(keys))
+	for _, key := range keys {
+		tags = append(tags, key+"="+record.Tags[key])
+	}
+	active, sla := "", ""
+	if record.IsActive != nil {
+		active = strconv.FormatBool(*record.IsActive)
+	}
+	if record.SLATarget != nil {
+		sla = strconv.FormatFloat(*record.SLATarget, 'f', -1, 64)
+	}
+	return []string{record.Name, record.IP, record.Method, optInt(record.Interval), optInt(record.NumOfRetry), active,
+		record.DeviceType, record.AlertChannel, optInt(record.ExpectedResponse), optString(record.HttpHeader),
+		optString(record.HttpBody), record.Severity, sla, record.Group, strings.Join(tags, ",")}
+}
+
+func exportHostRecord(host *models.Host, groupNames map[uint]string) hostRecord {
+	interval, retries, active, sla := host.Interval, host.NumOfRetry, host.IsActive, host.SLATarget
+	record := hostRecord{
+		Name:             host.Name,
+		IP:               host.IP,
+		Method:           host.Method.Method,
+		Interval:         &interval,
+		NumOfRetry:       &retries,
+		IsActive:         &active,
+		DeviceType:       host.DeviceTypeName,
+		AlertChannel:     host.AlertChannelName,
+		ExpectedResponse: host.ExpectedResponse,
+		HttpHeader:       host.HttpHeader,
+		HttpBody:         host.HttpBody,
+		Severity:         host.Severity,
+		SLATarget:        &sla,
+	}
+	if host.GroupID != nil {
+		record.Group = groupNames[*host.GroupID]
+	}
+	if len(host.Tags) > 0 {
+		record.Tags = map[string]string{}
+		for _, tag := range host.Tags {
+			record.Tags[tag.Key] = tag.Value
+		}
+	}
+	return record
+}
+
+// importLookups resolves the names used in records to the rows they refer to
+type importLookups struct {
+	methods map[string]uint
+	groups  map[string]uint
+}
+
+func loadImportLookups(db *gorm.DB) (*importLookups, error) {
+	lookups := &importLookups{methods: map[string]uint{}, groups: map[string]uint{}}
+
+	var methods []models.CheckConfig
+	if err := db.Find(&methods).Error; err != nil {
+		return nil, err
+	}
+	for _, method := range methods {
+		lookups.methods[method.Method] = method.ID
+	}
+	var groups []models.HostGroup
+	if err := db.Find(&groups).Error; err != nil {
+		return nil, err
+	}
+	for _, group := range groups {
+		lookups.groups[group.Name] = group.ID
+	}
+	return lookups, nil
+}
+
+// validate returns the problems of a record that show before it is applied to a host: values that did
+// not parse, names that resolve to nothing and malformed tags. validateHost checks the resulting host.
+func (l *importLookups) validate(record *hostRecord) map[string]string {
+	errs := map[string]string{}
+	for column, msg := range record.invalid {
+		errs[column] = msg
+	}
+	record.Name = strings.TrimSpace(record.Name)
+	record.IP = strings.TrimSpace(record.IP)
+	if record.Name == "" {
+		errs["name"] = "name is required"
+	}
+	if record.Method != "" {
+		if _, ok := l.methods[record.Method]; !ok {
+			errs["method"] = fmt.Sprintf("unknown check method %q", record.Method)
+		}
+	}
+	if record.Group != "" {
+		if _, ok := l.groups[record.Group]; !ok {
+			errs["group"] = fmt.Sprintf("unknown group %q", record.Group)
+		}
+	}
+	for key, value := range record.Tags {
+		if key == "" || strings.ContainsAny(key, "=,") || strings.Contains(value, ",") {
+			errs["tags"] = "tag keys cannot be empty or contain '=' or ',', values cannot contain ','"
+		}
+	}
+	return errs
+}
+
+// importHostColumns are the host columns a record can set, written on create and update
+var importHostColumns = []string{"name", "ip", "method_id", "interval", "num_of_retry", "is_active", "device_type_name",
+	"alert_channel_name", "expected_response", "http_header", "http_body", "severity", "sla_target", "group_id"}
+
+// importFieldNames maps the fields validateHost reports to the record's own names
+var importFieldNames = map[string]string{
+	"methodId":           "method",
+	"device_type_name":   "device_type",
+	"alert_channel_name": "alert_channel",
+	"group_id":           "group",
+}
+
+// importHostRecord upserts one record; it reports "create", "update" or "skip", or the problems of the host
+// the record would produce
+func importHostRecord(db *gorm.DB, record *hostRecord, match string, lookups *importLookups, dryRun bool) (string, uint, map[string]string, error) {
+	var host models.Host
+	query := db.Where("name = ?", record.Name)
+	if match == "ip" {
+		query = db.Where("ip = ?", record.IP)
+	}
+	err := query.Preload("Tags").First(&host).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return "", 0, nil, err
+	}
+	exists := err == nil
+	if exists && host.ManagedBy != "" {
+		return "", 0, nil, errors.New("host is managed by the configuration file and is read-only")
+	}
+	version := host.Version
+
+	before, _ := json.Marshal(hostSnapshot(&host))
+	applyHostRecord(&host, record, lookups)
+	after, _ := json.Marshal(hostSnapshot(&host))
+
+	action := "create"
+	if exists {
+		action = "update"
+		if bytes.Equal(before, after) {
+			return "skip", host.ID, nil, nil
+		}
+	} else if host.MethodID == 0 {
+		return "", 0, map[string]string{"method": "method is required for new hosts"}, nil
+	}
+	if errs := validateHost(db, &host); len(errs) > 0 {
+		fields := map[string]string{}
+		for field, msg := range errs {
+			if name, ok := importFieldNames[field]; ok {
+				field = name
+			}
+			fields[field] = msg
+		}
+		return "", 0, fields, nil
+	}
+	if dryRun {
+		return action, host.ID, nil, nil
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		tags := host.Tags
+		if exists {
+			if err := saveHostColumns(tx, &host, version, importHostColumns); err != nil {
+				return err
+			}
+		} else {
+			// Create swaps false and 0 for the column defaults, so write the record's values over them
+			values := hostColumnValues(&host, importHostColumns)
+			if err := tx.Omit("Tags").Create(&host).Error; err != nil {
+				return err
+			}
+			if err := tx.Model(&host).UpdateColumns(values).Error; err != nil {
+				return err
+			}
+		}
+		if record.Tags == nil {
+			return nil
+		}
+		if err := tx.Where("host_id = ?", host.ID).Delete(&models.HostTag{}).Error; err != nil {
+			return err
+		}
+		for i := range tags {
+			tags[i].ID = 0
+			tags[i].HostID = host.ID
+		}
+		if len(tags) == 0 {
+			return nil
+		}
+		return tx.Create(&tags).Error
+	})
+	return action, host.ID, nil, err
+}
+
+// applyHostRecord copies the fields present in the record onto the host
+func applyHostRecord(host *models.Host, record *hostRecord, lookups *importLookups) {
+	if host.ID == 0 {
+		// Defaults of a new host, as the database would set them
+		host.Interval, host.NumOfRetry, host.IsActive, host.Severity = 1, 3, true, "major"
+	}
+	host.Name = record.Name
+	host.IP = record.IP
+	if record.Method != "" {
+		host.MethodID = lookups.methods[record.Method]
+	}
+	if record.Interval != nil {
+		host.Interval = *record.Interval
+	}
+	if record.NumOfRetry != nil {
+		host.NumOfRetry = *record.NumOfRetry
+	}
+	if record.IsActive != nil {
+		host.IsActive = *record.IsActive
+	}
+	if record.DeviceType != "" {
+		host.DeviceTypeName = record.DeviceType
+	}
+	if record.AlertChannel != "" {
+		host.AlertChannelName = record.AlertChannel
+	}
+	if record.ExpectedResponse != nil {
+		host.ExpectedResponse = record.ExpectedResponse
+	}
+	if record.HttpHeader != nil {
+		host.HttpHeader = record.HttpHeader
+	}
+	if record.HttpBody != nil {
+		host.HttpBody = record.HttpBody
+	}
+	if record.Severity != "" {
+		host.Severity = record.Severity
+	}
+	if record.SLATarget != nil {
+		host.SLATarget = *record.SLATarget
+	}
+	if record.Group != "" {
+		id := lookups.groups[record.Group]
+		host.GroupID = &id
+	}
+	if record.Tags != nil {
+		host.Tags = host.Tags[:0:0]
+		for key, value := range record.Tags {
+			host.Tags = append(host.Tags, models.HostTag{HostID: host.ID, Key: key, Value: value})
+		}
+	}
+}
+
+// hostSnapshot lists the importable fields, used to detect rows that change nothing
+func hostSnapshot(host *models.Host) []interface{} {
+	tags := map[string]string{}
+	for _, tag := range host.Tags {
+		tags[tag.Key] = tag.Value
+	}
+	return []interface{}{host.Name, host.IP, host.MethodID, host.Interval, host.NumOfRetry, host.IsActive,
+		host.DeviceTypeName, host.AlertChannelName, host.ExpectedResponse, host.HttpHeader, host.HttpBody,
+		host.Severity, host.SLATarget, host.GroupID, tags}
+}
@@ -0,0 +1,335 @@
+package handlers
+
+import (
+	"alerting-app/database"
+	"alerting-app/models"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+// editableHost holds the host fields a merge patch may change, under the JSON names of models.Host
+type editableHost struct {
+	Name               string            `json:"name"`
+	IP                 string            `json:"ip"`
+	MethodID           uint              `json:"methodId"`
+	Interval           int               `json:"interval"`
+	NumOfRetry         int               `json:"num_of_retry"`
+	IsActive           bool              `json:"is_active"`
+	DeviceTypeName     string            `json:"device_type_name"`
+	AlertChannelName   string            `json:"alert_channel_name"`
+	HttpBody           *string           `json:"http_body"`
+	HttpHeader         *string           `json:"http_header"`
+	ExpectedResponse   *int              `json:"expected_response"`
+	EscalationPolicyID *uint             `json:"escalation_policy_id"`
+	ParentID           *uint             `json:"parent_id"`
+	MutedUntil         *time.Time        `json:"muted_until"`
+	Severity           string            `json:"severity"`
+	SLATarget          float64           `json:"sla_target"`
+	GroupID            *uint             `json:"group_id"`
+	Tags               map[string]string `json:"tags"`
+}
+
+// hostPatchColumns maps the patchable JSON fields to their columns; tags live in their own table
+var hostPatchColumns = map[string]string{
+	"name":                 "name",
+	"ip":                   "ip",
+	"methodId":             "method_id",
+	"interval":             "interval",
+	"num_of_retry":         "num_of_retry",
+	"is_active":            "is_active",
+	"device_type_name":     "device_type_name",
+	"alert_channel_name":   "alert_channel_name",
+	"http_body":            "http_body",
+	"http_header":          "http_header",
+	"expected_response":    "expected_response",
+	"escalation_policy_id": "escalation_policy_id",
+	"parent_id":            "parent_id",
+	"muted_until":          "muted_until",
+	"severity":             "severity",
+	"sla_target":           "sla_target",
+	"group_id":             "group_id",
+}
+
+// hostETag identifies one version of a host; checks do not change it
+func hostETag(host *models.Host) string {
+	return fmt.Sprintf(`"%d-%d"`, host.ID, host.Version)
+}
+
+// GetHost returns one host with its ETag, for use with PATCH
+func GetHost(c *fiber.Ctx) error {
+	db := database.DB
+
+	var host models.Host
+	if err := db.Preload("Tags").First(&host, c.Params("id")).Error; err != nil {
+		return c.Status(404).JSON(fiber.Map{
+			"error": "Host not found",
+		})
+	}
+	c.Set(fiber.HeaderETag, hostETag(&host))
+	return c.Status(200).JSON(host)
+}
+
+// PatchHost applies a JSON Merge Patch (RFC 7396) to a host; fields left out keep their value and null clears one.
+// Tags merge key by key. Send If-Match with the ETag, or "version" in the body, to fail with 409 when
+// the host was edited since it was read.
+func PatchHost(c *fiber.Ctx) error {
+	db := database.DB
+
+	var host models.Host
+	if err := db.Preload("Tags").First(&host, c.Params("id")).Error; err != nil {
+		return c.Status(404).JSON(fiber.Map{
+			"error": "Host not found",
+		})
+	}
+	if host.ManagedBy != "" {
+		return rejectManaged(c, host.ManagedBy, "Host")
+	}
+
+	var patch map[string]interface{}
+	if err := json.Unmarshal(c.Body(), &patch); err != nil || patch == nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "body must be a JSON object",
+		})
+	}
+
+	conflict := false
+	if match := c.Get(fiber.HeaderIfMatch); match != "" && match != "*" && match != hostETag(&host) {
+		conflict = true
+	}
+	if raw, ok := patch["version"]; ok {
+		delete(patch, "version")
+		seen, isNumber := raw.(float64)
+		if !isNumber || seen < 1 || seen != float64(uint(seen)) {
+			return c.Status(400).JSON(fiber.Map{
+				"error":  "Validation failed",
+				"fields": map[string]string{"version": "version must be a positive whole number"},
+			})
+		}
+		conflict = conflict || uint(seen) != host.Version
+	}
+	if conflict {
+		return hostConflict(c, &host)
+	}
+
+	errs := map[string]string{}
+	for key := range patch {
+		if _, ok := hostPatchColumns[key]; !ok && key != "tags" {
+			errs[key] = key + " is unknown or read-only"
+		}
+	}
+	if len(errs) > 0 {
+		return c.Status(400).JSON(fiber.Map{
+			"error":  "Validation failed",
+			"fields": errs,
+		})
+	}
+
+	updated, err := mergeHostPatch(&host, patch)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	if errs := validateHost(db, updated); len(errs) > 0 {
+		return c.Status(400).JSON(fiber.Map{
+			"error":  "Validation failed",
+			"fields": errs,
+		})
+	}
+	for _, tag := range updated.Tags {
+		if tag.Key == "" || strings.ContainsAny(tag.Key, "=,") || strings.Contains(tag.Value, ",") {
+			return c.Status(400).JSON(fiber.Map{
+				"error":  "Validation failed",
+				"fields": map[string]string{"tags": "tag keys cannot be empty or contain '=' or ',', values cannot contain ','"},
+			})
+		}
+	}
+
+	changes := map[string]interface{}{"version": models.NextHostVersion}
+	for key := range patch {
+		if column, ok := hostPatchColumns[key]; ok {
+			changes[column] = hostColumnValue(updated, column)
+		}
+	}
+	_, patchTags := patch["tags"]
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		// The version condition catches an edit that slipped in since the host was read
+		result := tx.Model(&models.Host{}).Where("id = ? AND version = ?", host.ID, host.Version).Updates(changes)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errHostConflict
+		}
+		if !patchTags {
+			return nil
+		}
+		if err := tx.Where("host_id = ?", host.ID).Delete(&models.HostTag{}).Error; err != nil {
+			return err
+		}
+		if len(updated.Tags) == 0 {
+			return nil
+		}
+		return tx.Create(&updated.Tags).Error
+	})
+	if errors.Is(err, errHostConflict) {
+		db.Preload("Tags").First(&host, host.ID)
+		return hostConflict(c, &host)
+	}
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	if err := db.Preload("Tags").First(&host, host.ID).Error; err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	c.Set(fiber.HeaderETag, hostETag(&host))
+	return c.Status(200).JSON(host)
+}
+
+var errHostConflict = errors.New("host was changed by another request")
+
+// hostConflict answers 409 with the current version so the client can merge and retry
+func hostConflict(c *fiber.Ctx, host *models.Host) error {
+	c.Set(fiber.HeaderETag, hostETag(host))
+	return c.Status(409).JSON(fiber.Map{
+		"error":   "Host was changed since it was read; reload and retry",
+		"current": host,
+	})
+}
+
+// mergeHostPatch returns a copy of the host with the merge patch applied to its editable fields
+func mergeHostPatch(host *models.Host, patch map[string]interface{}) (*models.Host, error) {
+	current := editableHost{
+		Name: host.Name, IP: host.IP, MethodID: host.MethodID, Interval: host.Interval, NumOfRetry: host.NumOfRetry,
+		IsActive: host.IsActive, DeviceTypeName: host.DeviceTypeName, AlertChannelName: host.AlertChannelName,
+		HttpBody: host.HttpBody, HttpHeader: host.HttpHeader, ExpectedResponse: host.ExpectedResponse,
+		EscalationPolicyID: host.EscalationPolicyID, ParentID: host.ParentID, MutedUntil: host.MutedUntil,
+		Severity: host.Severity, SLATarget: host.SLATarget, GroupID: host.GroupID, Tags: map[string]string{},
+	}
+	for _, tag := range host.Tags {
+		current.Tags[tag.Key] = tag.Value
+	}
+
+	var target map[string]interface{}
+	data, _ := json.Marshal(current)
+	json.Unmarshal(data, &target)
+	data, _ = json.Marshal(mergePatch(target, patch))
+
+	var merged editableHost
+	if err := json.Unmarshal(data, &merged); err != nil {
+		var typeErr *json.UnmarshalTypeError
+		if errors.As(err, &typeErr) {
+			return nil, fmt.Errorf("%s has the wrong type, expected %s", typeErr.Field, typeErr.Type)
+		}
+		return nil, err
+	}
+
+	updated := *host
+	updated.Name, updated.IP, updated.MethodID = merged.Name, merged.IP, merged.MethodID
+	updated.Interval, updated.NumOfRetry, updated.IsActive = merged.Interval, merged.NumOfRetry, merged.IsActive
+	updated.DeviceTypeName, updated.AlertChannelName = merged.DeviceTypeName, merged.AlertChannelName
+	updated.HttpBody, updated.HttpHeader, updated.ExpectedResponse = merged.HttpBody, merged.HttpHeader, merged.ExpectedResponse
+	updated.EscalationPolicyID, updated.ParentID, updated.MutedUntil = merged.EscalationPolicyID, merged.ParentID, merged.MutedUntil
+	updated.Severity, updated.SLATarget, updated.GroupID = merged.Severity, merged.SLATarget, merged.GroupID
+	updated.Tags = make([]models.HostTag, 0, len(merged.Tags))
+	for key, value := range merged.Tags {
+		updated.Tags = append(updated.Tags, models.HostTag{HostID: host.ID, Key: strings.TrimSpace(key), Value: strings.TrimSpace(value)})
+	}
+	return &updated, nil
+}
+
+// mergePatch applies RFC 7396: objects merge recursively, null removes a member, anything else replaces it
+func mergePatch(target interface{}, patch interface{}) interface{} {
+	patchObject, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+	targetObject, ok := target.(map[string]interface{})
+	if !ok {
+		targetObject = map[string]interface{}{}
+	}
+	for key, value := range patchObject {
+		if value == nil {
+			delete(targetObject, key)
+		} else {
+			targetObject[key] = mergePatch(targetObject[key], value)
+		}
+	}
+	return targetObject
+}
+
+// saveHostColumns writes the given columns of an edited host and bumps its version, leaving the
+// check state to the scheduler. It fails with errHostConflict when the host moved past version.
+func saveHostColumns(db *gorm.DB, host *models.Host, version uint, columns []string) error {
+	changes := hostColumnValues(host, columns)
+	changes["version"] = models.NextHostVersion
+	result := db.Model(&models.Host{}).Where("id = ? AND version = ?", host.ID, version).Updates(changes)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errHostConflict
+	}
+	return nil
+}
+
+// hostColumnValues reads the given patchable columns from the host, for a column update
+func hostColumnValues(host *models.Host, columns []string) map[string]interface{} {
+	values := map[string]interface{}{}
+	for _, column := range columns {
+		values[column] = hostColumnValue(host, column)
+	}
+	return values
+}
+
+// hostColumnValue reads the value of a patchable column from the host
+func hostColumnValue(host *models.Host, column string) interface{} {
+	switch column {
+	case "name":
+		return host.Name
+	case "ip":
+		return host.IP
+	case "method_id":
+		return host.MethodID
+	case "interval":
+		return host.Interval
+	case "num_of_retry":
+		return host.NumOfRetry
+	case "is_active":
+		return host.IsActive
+	case "device_type_name":
+		return host.DeviceTypeName
+	case "alert_channel_name":
+		return host.AlertChannelName
+	case "http_body":
+		return host.HttpBody
+	case "http_header":
+		return host.HttpHeader
+	case "expected_response":
+		return host.ExpectedResponse
+	case "escalation_policy_id":
+		return host.EscalationPolicyID
+	case "parent_id":
+		return host.ParentID
+	case "muted_until":
+		return host.MutedUntil
+	case "severity":
+		return host.Severity
+	case "sla_target":
+		return host.SLATarget
+	default:
+		return host.GroupID
+	}
+}
@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"alerting-app/jobs"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+// hostStatusConditions express the host states of the dashboard and the group rollups
+var hostStatusConditions = map[string]string{
+	"paused":  "is_active = false",
+	"down":    "is_active = true AND alert_status = true",
+	"pending": "is_active = true AND alert_status = false AND is_pending = true",
+	"up":      "is_active = true AND alert_status = false AND is_pending = false",
+}
+
+// hostStatusSince is when the host entered its current state: its last state change, or its creation
+const hostStatusSince = "COALESCE((SELECT MAX(host_histories.checked_at) FROM host_histories WHERE host_histories.host_id = hosts.id), hosts.created_at)"
+
+// hostSortColumns maps ?sort= to an ORDER BY expression
+var hostSortColumns = map[string]string{
+	"id":           "hosts.id",
+	"name":         "hosts.name",
+	"ip":           "hosts.ip",
+	"device_type":  "hosts.device_type_name",
+	"method":       "hosts.method_id",
+	"interval":     "hosts.interval",
+	"severity":     "FIELD(hosts.severity, 'info', 'minor', 'major', 'critical')",
+	"created_at":   "hosts.created_at",
+	"last_checked": "hosts.last_checked_date",
+	// paused, up, pending, down
+	"status":          "CASE WHEN NOT hosts.is_active THEN 0 WHEN hosts.alert_status THEN 3 WHEN hosts.is_pending THEN 2 ELSE 1 END",
+	"status_duration": hostStatusSince,
+}
+
+// reversedHostSorts sort their expression the other way: ascending status_duration is the latest change first
+var reversedHostSorts = map[string]bool{"status_duration": true}
+
+// filterHosts applies the optional host filters to a hosts query:
+// ?q= (name or IP), ?status=up|down|pending|paused, ?device_type=, ?method=ping|http_get|http_post,
+// ?active=true|false, ?group_id= (includes subgroups) and ?tags=site=ub-hq,floor=3
+func filterHosts(c *fiber.Ctx, db *gorm.DB, query *gorm.DB) (*gorm.DB, error) {
+	if q := strings.TrimSpace(c.Query("q")); q != "" {
+		like := "%" + escapeLike(q) + "%"
+		query = query.Where("(hosts.name LIKE ? OR hosts.ip LIKE ?)", like, like)
+	}
+	if status := c.Query("status"); status != "" {
+		var conditions []string
+		for _, value := range strings.Split(status, ",") {
+			condition, ok := hostStatusConditions[strings.TrimSpace(value)]
+			if !ok {
+				return nil, fmt.Errorf("status must be up, down, pending or paused")
+			}
+			conditions = append(conditions, "("+condition+")")
+		}
+		query = query.Where(strings.Join(conditions, " OR "))
+	}
+	if deviceType := c.Query("device_type"); deviceType != "" {
+		query = query.Where("device_type_name = ?", deviceType)
+	}
+	if method := c.Query("method"); method != "" {
+		query = query.Where("method_id IN (SELECT id FROM check_configs WHERE method = ?)", method)
+	}
+	if active := c.Query("active"); active != "" {
+		flag, err := strconv.ParseBool(active)
+		if err != nil {
+			return nil, fmt.Errorf("invalid active %q", active)
+		}
+		query = query.Where("is_active = ?", flag)
+	}
+	if groupID := c.QueryInt("group_id"); groupID > 0 {
+		var err error
+		if query, err = jobs.WhereGroup(db, query, uint(groupID)); err != nil {
+			return nil, err
+		}
+	}
+	if selector := c.Query("tags"); selector != "" {
+		terms, err := jobs.ParseTagSelector(selector)
+		if err != nil {
+			return nil, err
+		}
+		query = jobs.WhereTags(db, query, terms)
+	}
+	return query, nil
+}
+
+// sortHosts orders a hosts query by ?sort= and ?order=, then by id
+func sortHosts(c *fiber.Ctx, query *gorm.DB) (*gorm.DB, error) {
+	sort := c.Query("sort", "id")
+	column, ok := hostSortColumns[sort]
+	if !ok {
+		return nil, fmt.Errorf("sort must be one of id, name, ip, device_type, method, interval, severity, created_at, last_checked, status or status_duration")
+	}
+	direction, columnDirection := "ASC", "ASC"
+	if c.Query("order", "asc") == "desc" {
+		direction = "DESC"
+	}
+	if (direction == "DESC") != reversedHostSorts[sort] {
+		columnDirection = "DESC"
+	}
+	return query.Order(column + " " + columnDirection).Order("hosts.id " + direction), nil
+}
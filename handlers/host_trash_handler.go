@@ -0,0 +1,155 @@
+package handlers
+
+import (
+	"alerting-app/database"
+	"alerting-app/models"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+// purgeTokenTTL is how long a purge confirmation token stays valid
+const purgeTokenTTL = 5 * time.Minute
+
+// hostPurgeTables lists the rows that belong to a host and go with it on purge
+var hostPurgeTables = []struct {
+	name  string
+	model interface{}
+}{
+	{"history", &models.HostHistory{}},
+	{"check_results", &models.CheckResult{}},
+	{"check_rollups", &models.CheckRollup{}},
+	{"incidents", &models.Incident{}},
+	{"notifications", &models.Notification{}},
+	{"tags", &models.HostTag{}},
+}
+
+// GetDeletedHosts lists soft-deleted hosts, most recently deleted first
+func GetDeletedHosts(c *fiber.Ctx) error {
+	db := database.DB
+
+	var hosts []models.Host
+	if err := db.Unscoped().Preload("Tags").Where("deleted_at IS NOT NULL").Order("deleted_at DESC").Find(&hosts).Error; err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	return c.Status(200).JSON(hosts)
+}
+
+// RestoreHost undeletes a host; the scheduler picks it up again on its next tick.
+// A host the configuration file had pruned comes back unmanaged.
+func RestoreHost(c *fiber.Ctx) error {
+	db := database.DB
+
+	var host models.Host
+	if err := db.Unscoped().Where("deleted_at IS NOT NULL").First(&host, c.Params("id")).Error; err != nil {
+		return c.Status(404).JSON(fiber.Map{
+			"error": "Deleted host not found",
+		})
+	}
+	err := db.Unscoped().Model(&host).Updates(map[string]interface{}{"deleted_at": nil, "managed_by": "", "version": models.NextHostVersion}).Error
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	if err := db.Preload("Tags").First(&host, host.ID).Error; err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	return c.Status(200).JSON(host)
+}
+
+// PurgeHost permanently removes a soft-deleted host with its history, results, incidents and notifications.
+// The first call answers 428 with a confirm_token and what would be removed; repeat it with ?confirm=<token>.
+func PurgeHost(c *fiber.Ctx) error {
+	db := database.DB
+
+	var host models.Host
+	if err := db.Unscoped().Where("deleted_at IS NOT NULL").First(&host, c.Params("id")).Error; err != nil {
+		return c.Status(404).JSON(fiber.Map{
+			"error": "Deleted host not found; delete the host before purging it",
+		})
+	}
+	secret := c.Locals("jwtSecret").(string)
+
+	counts := map[string]int64{}
+	for _, table := range hostPurgeTables {
+		var count int64
+		db.Model(table.model).Where("host_id = ?", host.ID).Count(&count)
+		counts[table.name] = count
+	}
+
+	confirm := c.Query("confirm")
+	if confirm == "" {
+		expires := time.Now().Add(purgeTokenTTL)
+		return c.Status(fiber.StatusPreconditionRequired).JSON(fiber.Map{
+			"error":         "Purging cannot be undone; repeat the request with ?confirm=<confirm_token>",
+			"confirm_token": purgeToken(secret, &host, expires),
+			"expires_at":    expires,
+			"will_delete":   counts,
+		})
+	}
+	if !validPurgeToken(secret, &host, confirm) {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "confirm token is invalid or expired",
+		})
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		for _, table := range hostPurgeTables {
+			if err := tx.Unscoped().Where("host_id = ?", host.ID).Delete(table.model).Error; err != nil {
+				return err
+			}
+		}
+		// Windows and status page components scoped to the host would otherwise point at nothing
+		if err := tx.Unscoped().Where("host_id = ?", host.ID).Delete(&models.MaintenanceWindow{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("host_id = ?", host.ID).Delete(&models.StatusPageComponent{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Model(&models.Host{}).Where("parent_id = ?", host.ID).Update("parent_id", nil).Error; err != nil {
+			return err
+		}
+		return tx.Unscoped().Delete(&host).Error
+	})
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	return c.Status(200).JSON(fiber.Map{
+		"message": "Host permanently deleted",
+		"deleted": counts,
+	})
+}
+
+// purgeToken signs the host and its deletion time, so restoring and deleting again needs a new token
+func purgeToken(secret string, host *models.Host, expires time.Time) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "purge:%d:%d:%d", host.ID, host.DeletedAt.Time.UnixMilli(), expires.Unix())
+	return strconv.FormatInt(expires.Unix(), 10) + "." + hex.EncodeToString(mac.Sum(nil))
+}
+
+func validPurgeToken(secret string, host *models.Host, token string) bool {
+	unix, _, found := strings.Cut(token, ".")
+	seconds, err := strconv.ParseInt(unix, 10, 64)
+	if !found || err != nil {
+		return false
+	}
+	expires := time.Unix(seconds, 0)
+	if time.Now().After(expires) {
+		return false
+	}
+	return hmac.Equal([]byte(token), []byte(purgeToken(secret, host, expires)))
+}
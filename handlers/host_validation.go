@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"alerting-app/jobs"
+	"alerting-app/models"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// validateHost checks a host before it is saved and returns the problems keyed by JSON field
+func validateHost(db *gorm.DB, host *models.Host) map[string]string {
+	errs := map[string]string{}
+	host.Name = strings.TrimSpace(host.Name)
+	host.IP = strings.TrimSpace(host.IP)
+	if host.Name == "" {
+		errs["name"] = "name is required"
+	}
+
+	var method models.CheckConfig
+	if host.MethodID == 0 {
+		errs["methodId"] = "methodId is required"
+	} else if err := db.First(&method, host.MethodID).Error; err != nil {
+		errs["methodId"] = "methodId does not exist"
+	}
+	if host.IP == "" {
+		errs["ip"] = "ip is required"
+	} else if err := jobs.ValidateCheckTarget(method.Method, host.IP); err != nil {
+		errs["ip"] = "ip " + err.Error()
+	}
+
+	if host.Interval < 1 {
+		errs["interval"] = "interval must be at least 1 minute"
+	}
+	if host.NumOfRetry < 0 {
+		errs["num_of_retry"] = "num_of_retry cannot be negative"
+	}
+	if code := host.ExpectedResponse; code != nil && (*code < 100 || *code > 599) {
+		errs["expected_response"] = "expected_response must be an HTTP status code"
+	}
+	if err := jobs.ValidateHeaders(host.HttpHeader); err != nil {
+		errs["http_header"] = "http_header " + err.Error()
+	}
+	if host.Severity != "" {
+		if _, ok := models.SeverityLevels[host.Severity]; !ok {
+			errs["severity"] = "severity must be one of info, minor, major or critical"
+		}
+	}
+	if host.SLATarget < 0 || host.SLATarget > 100 {
+		errs["sla_target"] = "sla_target must be a percentage between 0 and 100"
+	}
+
+	if host.DeviceTypeName != "" {
+		var deviceType models.DeviceType
+		if err := db.Where("dev_type = ?", host.DeviceTypeName).First(&deviceType).Error; err != nil {
+			errs["device_type_name"] = "device_type_name does not exist"
+		}
+	}
+	if host.AlertChannelName != "" {
+		var channel models.AlertChannel
+		if err := db.Where("name = ?", host.AlertChannelName).First(&channel).Error; err != nil {
+			errs["alert_channel_name"] = "alert_channel_name does not exist"
+		}
+	}
+	if host.EscalationPolicyID != nil {
+		var policy models.EscalationPolicy
+		if err := db.First(&policy, *host.EscalationPolicyID).Error; err != nil {
+			errs["escalation_policy_id"] = "escalation_policy_id does not exist"
+		}
+	}
+	if host.ParentID != nil {
+		var parent models.Host
+		if host.ID != 0 && *host.ParentID == host.ID {
+			errs["parent_id"] = "a host cannot be its own parent"
+		} else if err := db.First(&parent, *host.ParentID).Error; err != nil {
+			errs["parent_id"] = "parent_id does not exist"
+		}
+	}
+	if host.GroupID != nil {
+		var group models.HostGroup
+		if err := db.First(&group, *host.GroupID).Error; err != nil {
+			errs["group_id"] = "group_id does not exist"
+		}
+	}
+	return errs
+}
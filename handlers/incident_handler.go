@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"alerting-app/database"
+	"alerting-app/jobs"
+	"alerting-app/models"
+	"fmt"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func GetIncidents(c *fiber.Ctx) error {
+	db := database.DB
+	var incidents []models.Incident
+
+	page := c.QueryInt("page", 1)
+	limit := c.QueryInt("limit", 20)
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 {
+		limit = 20
+	}
+	offset := (page - 1) * limit
+
+	query := db.Model(&models.Incident{})
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if hostID := c.QueryInt("host_id"); hostID > 0 {
+		query = query.Where("host_id = ?", hostID)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Error counting incidents",
+		})
+	}
+	if err := query.Order("opened_at DESC").Limit(limit).Offset(offset).Find(&incidents).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Error fetching incidents",
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"data":       incidents,
+		"page":       page,
+		"limit":      limit,
+		"total":      total,
+		"totalPages": (total + int64(limit) - 1) / int64(limit),
+	})
+}
+
+// AcknowledgeIncident stops escalation for an incident on behalf of the caller
+func AcknowledgeIncident(c *fiber.Ctx) error {
+	db := database.DB
+
+	var incident models.Incident
+	if err := db.First(&incident, c.Params("id")).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Incident not found",
+		})
+	}
+
+	by := fmt.Sprint(c.Locals("username"))
+	if err := jobs.AcknowledgeIncident(&incident, by); err != nil {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	return c.Status(fiber.StatusOK).JSON(incident)
+}
@@ -0,0 +1,131 @@
+package handlers
+
+import (
+	"alerting-app/database"
+	"alerting-app/models"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// GetMaintenanceWindows lists windows; ?active=true limits the list to the ones in effect now
+func GetMaintenanceWindows(c *fiber.Ctx) error {
+	db := database.DB
+	var windows []models.MaintenanceWindow
+
+	query := db.Order("starts_at DESC")
+	if c.QueryBool("active") {
+		now := time.Now()
+		query = query.Where("starts_at <= ? AND ends_at > ?", now, now)
+	}
+	if err := query.Find(&windows).Error; err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	return c.Status(200).JSON(windows)
+}
+
+func CreateMaintenanceWindow(c *fiber.Ctx) error {
+	db := database.DB
+
+	window := new(models.MaintenanceWindow)
+	if err := c.BodyParser(window); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	window.ID = 0
+	window.ManagedBy = ""
+	if msg := validateMaintenanceWindow(window); msg != "" {
+		return c.Status(400).JSON(fiber.Map{
+			"error": msg,
+		})
+	}
+
+	if err := db.Create(window).Error; err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	return c.Status(200).JSON(window)
+}
+
+func UpdateMaintenanceWindow(c *fiber.Ctx) error {
+	db := database.DB
+
+	var window models.MaintenanceWindow
+	if err := db.First(&window, c.Params("id")).Error; err != nil {
+		return c.Status(404).JSON(fiber.Map{
+			"error": "Maintenance window not found",
+		})
+	}
+	if window.ManagedBy != "" {
+		return rejectManaged(c, window.ManagedBy, "Maintenance window")
+	}
+
+	var update models.MaintenanceWindow
+	if err := c.BodyParser(&update); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	if msg := validateMaintenanceWindow(&update); msg != "" {
+		return c.Status(400).JSON(fiber.Map{
+			"error": msg,
+		})
+	}
+
+	window.Name = update.Name
+	window.HostID = update.HostID
+	window.DeviceTypeName = update.DeviceTypeName
+	window.StartsAt = update.StartsAt
+	window.EndsAt = update.EndsAt
+	window.Reason = update.Reason
+	window.GroupID = update.GroupID
+	window.TagSelector = update.TagSelector
+	if err := db.Save(&window).Error; err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	return c.Status(200).JSON(window)
+}
+
+func DeleteMaintenanceWindow(c *fiber.Ctx) error {
+	db := database.DB
+
+	var window models.MaintenanceWindow
+	if err := db.First(&window, c.Params("id")).Error; err != nil {
+		return c.Status(404).JSON(fiber.Map{
+			"error": "Maintenance window not found",
+		})
+	}
+	if window.ManagedBy != "" {
+		return rejectManaged(c, window.ManagedBy, "Maintenance window")
+	}
+	if err := db.Delete(&window).Error; err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	return c.Status(200).JSON(fiber.Map{
+		"message": "Maintenance window deleted",
+	})
+}
+
+func validateMaintenanceWindow(window *models.MaintenanceWindow) string {
+	if window.StartsAt.IsZero() || window.EndsAt.IsZero() {
+		return "starts_at and ends_at are required"
+	}
+	if !window.EndsAt.After(window.StartsAt) {
+		return "ends_at must be after starts_at"
+	}
+	if window.HostID != nil {
+		var host models.Host
+		if err := database.DB.First(&host, *window.HostID).Error; err != nil {
+			return "host_id does not exist"
+		}
+	}
+	return validateHostSelectors(window.GroupID, window.TagSelector)
+}
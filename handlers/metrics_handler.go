@@ -0,0 +1,36 @@
+package handlers
+
+import (
+	"alerting-app/config"
+	"alerting-app/jobs"
+	"bytes"
+	"crypto/subtle"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// GetMetrics serves checker metrics in the Prometheus text format.
+// When METRICS_TOKEN is set the scraper must send it as a bearer token or ?token=
+func GetMetrics(c *fiber.Ctx) error {
+	if token := config.Config("METRICS_TOKEN"); token != "" {
+		given := strings.TrimPrefix(c.Get("Authorization"), "Bearer ")
+		if given == "" {
+			given = c.Query("token")
+		}
+		if subtle.ConstantTimeCompare([]byte(given), []byte(token)) != 1 {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error": "Invalid metrics token",
+			})
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := jobs.WriteMetrics(&buf); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to collect metrics",
+		})
+	}
+	c.Set(fiber.HeaderContentType, "text/plain; version=0.0.4; charset=utf-8")
+	return c.Send(buf.Bytes())
+}
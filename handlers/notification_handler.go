@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"alerting-app/database"
+	"alerting-app/jobs"
+	"alerting-app/models"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// GetNotifications lists outbox entries, newest first
+func GetNotifications(c *fiber.Ctx) error {
+	db := database.DB
+	var notifications []models.Notification
+
+	page := c.QueryInt("page", 1)
+	limit := c.QueryInt("limit", 20)
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 {
+		limit = 20
+	}
+	offset := (page - 1) * limit
+
+	query := db.Model(&models.Notification{})
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if hostID := c.QueryInt("host_id"); hostID > 0 {
+		query = query.Where("host_id = ?", hostID)
+	}
+	if channel := c.Query("channel"); channel != "" {
+		query = query.Where("channel_name = ?", channel)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Error counting notifications",
+		})
+	}
+
+	if err := query.Order("created_at DESC").Limit(limit).Offset(offset).Find(&notifications).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Error fetching notifications",
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"data":       notifications,
+		"page":       page,
+		"limit":      limit,
+		"total":      total,
+		"totalPages": (total + int64(limit) - 1) / int64(limit),
+	})
+}
+
+// ResendNotification requeues a notification and delivers it right away
+func ResendNotification(c *fiber.Ctx) error {
+	db := database.DB
+
+	var notification models.Notification
+	if err := db.First(&notification, c.Params("id")).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Notification not found",
+		})
+	}
+
+	if err := jobs.ResendNotification(&notification); err != nil {
+		return c.Status(fiber.StatusBadGateway).JSON(fiber.Map{
+			"error":        err.Error(),
+			"notification": notification,
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(notification)
+}
@@ -0,0 +1,263 @@
+package handlers
+
+import (
+	"alerting-app/database"
+	"alerting-app/jobs"
+	"alerting-app/models"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+func GetOnCallSchedules(c *fiber.Ctx) error {
+	db := database.DB
+	var schedules []models.OnCallSchedule
+	if err := db.Preload("Members", func(db *gorm.DB) *gorm.DB {
+		return db.Order("position ASC")
+	}).Preload("Members.User").Preload("Overrides.User").Find(&schedules).Error; err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	return c.Status(200).JSON(schedules)
+}
+
+func CreateOnCallSchedule(c *fiber.Ctx) error {
+	db := database.DB
+
+	schedule := new(models.OnCallSchedule)
+	if err := c.BodyParser(schedule); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	schedule.Overrides = nil
+	if msg := validateOnCallSchedule(schedule); msg != "" {
+		return c.Status(400).JSON(fiber.Map{
+			"error": msg,
+		})
+	}
+
+	if err := db.Omit("Members.User").Create(schedule).Error; err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	return c.Status(200).JSON(schedule)
+}
+
+// UpdateOnCallSchedule replaces the schedule settings and its member rotation
+func UpdateOnCallSchedule(c *fiber.Ctx) error {
+	db := database.DB
+
+	var schedule models.OnCallSchedule
+	if err := db.First(&schedule, c.Params("id")).Error; err != nil {
+		return c.Status(404).JSON(fiber.Map{
+			"error": "Schedule not found",
+		})
+	}
+
+	var update models.OnCallSchedule
+	if err := c.BodyParser(&update); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	if msg := validateOnCallSchedule(&update); msg != "" {
+		return c.Status(400).JSON(fiber.Map{
+			"error": msg,
+		})
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		schedule.Name = update.Name
+		schedule.Timezone = update.Timezone
+		schedule.RotationStart = update.RotationStart
+		schedule.HandoffTime = update.HandoffTime
+		schedule.RotationDays = update.RotationDays
+		if err := tx.Save(&schedule).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("schedule_id = ?", schedule.ID).Delete(&models.OnCallMember{}).Error; err != nil {
+			return err
+		}
+		for i := range update.Members {
+			update.Members[i].ID = 0
+			update.Members[i].ScheduleID = schedule.ID
+		}
+		if len(update.Members) > 0 {
+			if err := tx.Omit("User").Create(&update.Members).Error; err != nil {
+				return err
+			}
+		}
+		schedule.Members = update.Members
+		return nil
+	})
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	return c.Status(200).JSON(schedule)
+}
+
+func DeleteOnCallSchedule(c *fiber.Ctx) error {
+	db := database.DB
+
+	var schedule models.OnCallSchedule
+	if err := db.First(&schedule, c.Params("id")).Error; err != nil {
+		return c.Status(404).JSON(fiber.Map{
+			"error": "Schedule not found",
+		})
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("schedule_id = ?", schedule.ID).Delete(&models.OnCallMember{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("schedule_id = ?", schedule.ID).Delete(&models.OnCallOverride{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.EscalationStep{}).Where("schedule_id = ?", schedule.ID).Update("schedule_id", nil).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&schedule).Error
+	})
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	return c.Status(200).JSON(fiber.Map{
+		"message": "Schedule deleted",
+	})
+}
+
+func CreateOnCallOverride(c *fiber.Ctx) error {
+	db := database.DB
+
+	var schedule models.OnCallSchedule
+	if err := db.First(&schedule, c.Params("id")).Error; err != nil {
+		return c.Status(404).JSON(fiber.Map{
+			"error": "Schedule not found",
+		})
+	}
+
+	override := new(models.OnCallOverride)
+	if err := c.BodyParser(override); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	override.ID = 0
+	override.ScheduleID = schedule.ID
+	if override.UserID == 0 || !override.EndsAt.After(override.StartsAt) {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "user_id is required and ends_at must be after starts_at",
+		})
+	}
+
+	if err := db.Omit("User").Create(override).Error; err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	return c.Status(200).JSON(override)
+}
+
+func DeleteOnCallOverride(c *fiber.Ctx) error {
+	db := database.DB
+
+	var override models.OnCallOverride
+	if err := db.Where("id = ? AND schedule_id = ?", c.Params("overrideId"), c.Params("id")).First(&override).Error; err != nil {
+		return c.Status(404).JSON(fiber.Map{
+			"error": "Override not found",
+		})
+	}
+	if err := db.Delete(&override).Error; err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	return c.Status(200).JSON(fiber.Map{
+		"message": "Override deleted",
+	})
+}
+
+// GetOnCall reports who is on call for every schedule at ?at= (RFC3339, default now)
+func GetOnCall(c *fiber.Ctx) error {
+	db := database.DB
+
+	at := time.Now()
+	if value := c.Query("at"); value != "" {
+		parsed, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "at must be an RFC3339 timestamp",
+			})
+		}
+		at = parsed
+	}
+
+	var schedules []models.OnCallSchedule
+	query := db
+	if id := c.Params("id"); id != "" {
+		query = query.Where("id = ?", id)
+	}
+	if err := query.Find(&schedules).Error; err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	response := []fiber.Map{}
+	for i := range schedules {
+		user, err := jobs.WhoIsOnCall(db, &schedules[i], at)
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		response = append(response, fiber.Map{
+			"schedule_id": schedules[i].ID,
+			"schedule":    schedules[i].Name,
+			"at":          at,
+			"user":        user,
+		})
+	}
+	return c.Status(200).JSON(response)
+}
+
+func validateOnCallSchedule(schedule *models.OnCallSchedule) string {
+	if schedule.Name == "" {
+		return "name is required"
+	}
+	if schedule.Timezone == "" {
+		schedule.Timezone = "UTC"
+	}
+	if _, err := time.LoadLocation(schedule.Timezone); err != nil {
+		return "timezone is not a valid IANA zone"
+	}
+	if schedule.HandoffTime == "" {
+		schedule.HandoffTime = "09:00"
+	}
+	if _, err := time.Parse("15:04", schedule.HandoffTime); err != nil {
+		return "handoff_time must be formatted as HH:MM"
+	}
+	if schedule.RotationDays <= 0 {
+		schedule.RotationDays = 7
+	}
+	if schedule.RotationStart.IsZero() {
+		schedule.RotationStart = time.Now()
+	}
+	for i, member := range schedule.Members {
+		if member.UserID == 0 {
+			return "every member needs a user_id"
+		}
+		if member.Position == 0 {
+			schedule.Members[i].Position = i + 1
+		}
+	}
+	return ""
+}
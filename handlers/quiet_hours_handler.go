@@ -0,0 +1,131 @@
+package handlers
+
+import (
+	"alerting-app/database"
+	"alerting-app/jobs"
+	"alerting-app/models"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func GetQuietHours(c *fiber.Ctx) error {
+	db := database.DB
+	var rules []models.QuietHours
+	if err := db.Find(&rules).Error; err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	// Tell the caller which rules are in effect right now
+	now := time.Now()
+	response := make([]fiber.Map, 0, len(rules))
+	for _, rule := range rules {
+		end, active, _ := jobs.QuietPeriodEnd(rule, now)
+		item := fiber.Map{"rule": rule, "active": active}
+		if active {
+			item["ends_at"] = end
+		}
+		response = append(response, item)
+	}
+	return c.Status(200).JSON(response)
+}
+
+func CreateQuietHours(c *fiber.Ctx) error {
+	db := database.DB
+
+	rule := new(models.QuietHours)
+	if err := c.BodyParser(rule); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	rule.ID = 0
+	if msg := validateQuietHours(rule); msg != "" {
+		return c.Status(400).JSON(fiber.Map{
+			"error": msg,
+		})
+	}
+
+	if err := db.Create(rule).Error; err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	return c.Status(200).JSON(rule)
+}
+
+func UpdateQuietHours(c *fiber.Ctx) error {
+	db := database.DB
+
+	var rule models.QuietHours
+	if err := db.First(&rule, c.Params("id")).Error; err != nil {
+		return c.Status(404).JSON(fiber.Map{
+			"error": "Quiet hours rule not found",
+		})
+	}
+
+	var update models.QuietHours
+	if err := c.BodyParser(&update); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	if msg := validateQuietHours(&update); msg != "" {
+		return c.Status(400).JSON(fiber.Map{
+			"error": msg,
+		})
+	}
+
+	rule.ChannelName = update.ChannelName
+	rule.UserID = update.UserID
+	rule.Start = update.Start
+	rule.End = update.End
+	rule.Timezone = update.Timezone
+	rule.MinSeverity = update.MinSeverity
+	if err := db.Save(&rule).Error; err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	return c.Status(200).JSON(rule)
+}
+
+func DeleteQuietHours(c *fiber.Ctx) error {
+	db := database.DB
+
+	var rule models.QuietHours
+	if err := db.First(&rule, c.Params("id")).Error; err != nil {
+		return c.Status(404).JSON(fiber.Map{
+			"error": "Quiet hours rule not found",
+		})
+	}
+	if err := db.Delete(&rule).Error; err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	return c.Status(200).JSON(fiber.Map{
+		"message": "Quiet hours rule deleted",
+	})
+}
+
+func validateQuietHours(rule *models.QuietHours) string {
+	if rule.ChannelName == "" && rule.UserID == nil {
+		return "channel_name or user_id is required"
+	}
+	if rule.Timezone == "" {
+		rule.Timezone = "UTC"
+	}
+	if rule.MinSeverity == "" {
+		rule.MinSeverity = "critical"
+	}
+	if _, ok := models.SeverityLevels[rule.MinSeverity]; !ok {
+		return "min_severity must be one of info, minor, major or critical"
+	}
+	if _, _, err := jobs.QuietPeriodEnd(*rule, time.Now()); err != nil {
+		return err.Error()
+	}
+	return ""
+}
@@ -0,0 +1,210 @@
+package handlers
+
+import (
+	"alerting-app/database"
+	"alerting-app/jobs"
+	"alerting-app/models"
+	"net/mail"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func GetReportDefinitions(c *fiber.Ctx) error {
+	db := database.DB
+	var defs []models.ReportDefinition
+	if err := db.Order("name ASC").Find(&defs).Error; err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	return c.Status(200).JSON(defs)
+}
+
+func CreateReportDefinition(c *fiber.Ctx) error {
+	db := database.DB
+
+	// Enabled unless the body says otherwise
+	def := &models.ReportDefinition{Enabled: true}
+	if err := c.BodyParser(def); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	def.ID = 0
+	def.LastRunAt = nil
+	def.LastError = ""
+	if msg := validateReportDefinition(def); msg != "" {
+		return c.Status(400).JSON(fiber.Map{
+			"error": msg,
+		})
+	}
+	def.NextRunAt = jobs.NextReportRun(def, time.Now())
+
+	if err := db.Create(def).Error; err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	return c.Status(200).JSON(def)
+}
+
+func UpdateReportDefinition(c *fiber.Ctx) error {
+	db := database.DB
+
+	var def models.ReportDefinition
+	if err := db.First(&def, c.Params("id")).Error; err != nil {
+		return c.Status(404).JSON(fiber.Map{
+			"error": "Report definition not found",
+		})
+	}
+
+	var update models.ReportDefinition
+	if err := c.BodyParser(&update); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	if msg := validateReportDefinition(&update); msg != "" {
+		return c.Status(400).JSON(fiber.Map{
+			"error": msg,
+		})
+	}
+
+	periodChanged := def.Period != update.Period
+	def.Name = update.Name
+	def.Period = update.Period
+	def.GroupBy = update.GroupBy
+	def.HostIDs = update.HostIDs
+	def.DeviceType = update.DeviceType
+	def.GroupID = update.GroupID
+	def.TagSelector = update.TagSelector
+	def.Format = update.Format
+	def.Recipients = update.Recipients
+	def.ChannelName = update.ChannelName
+	def.Enabled = update.Enabled
+	if periodChanged {
+		def.NextRunAt = jobs.NextReportRun(&def, time.Now())
+	}
+	if err := db.Save(&def).Error; err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	return c.Status(200).JSON(def)
+}
+
+func DeleteReportDefinition(c *fiber.Ctx) error {
+	db := database.DB
+
+	var def models.ReportDefinition
+	if err := db.First(&def, c.Params("id")).Error; err != nil {
+		return c.Status(404).JSON(fiber.Map{
+			"error": "Report definition not found",
+		})
+	}
+	if err := db.Unscoped().Delete(&def).Error; err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	return c.Status(200).JSON(fiber.Map{
+		"message": "Report definition deleted",
+	})
+}
+
+// RenderReportDefinition returns the document for the last complete period without delivering it
+func RenderReportDefinition(c *fiber.Ctx) error {
+	db := database.DB
+
+	var def models.ReportDefinition
+	if err := db.First(&def, c.Params("id")).Error; err != nil {
+		return c.Status(404).JSON(fiber.Map{
+			"error": "Report definition not found",
+		})
+	}
+	if format := c.Query("format"); format != "" {
+		def.Format = format
+	}
+
+	generated, err := jobs.GenerateReport(db, &def, time.Now())
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	c.Set(fiber.HeaderContentType, generated.ContentType)
+	if c.QueryBool("download") {
+		c.Set(fiber.HeaderContentDisposition, `attachment; filename="`+generated.Filename+`"`)
+	}
+	return c.Send(generated.Content)
+}
+
+// RunReportDefinitionNow generates and delivers the report immediately
+func RunReportDefinitionNow(c *fiber.Ctx) error {
+	db := database.DB
+
+	var def models.ReportDefinition
+	if err := db.First(&def, c.Params("id")).Error; err != nil {
+		return c.Status(404).JSON(fiber.Map{
+			"error": "Report definition not found",
+		})
+	}
+
+	if err := jobs.RunReportDefinition(&def); err != nil {
+		return c.Status(502).JSON(fiber.Map{
+			"error":      err.Error(),
+			"definition": def,
+		})
+	}
+	return c.Status(200).JSON(def)
+}
+
+func validateReportDefinition(def *models.ReportDefinition) string {
+	def.Name = strings.TrimSpace(def.Name)
+	if def.Name == "" {
+		return "name is required"
+	}
+	if def.Period == "" {
+		def.Period = "weekly"
+	}
+	if def.Period != "weekly" && def.Period != "monthly" {
+		return "period must be weekly or monthly"
+	}
+	if def.GroupBy == "" {
+		def.GroupBy = "host"
+	}
+	if def.GroupBy != "host" && def.GroupBy != "device_type" && def.GroupBy != "group" &&
+		(!strings.HasPrefix(def.GroupBy, "tag:") || def.GroupBy == "tag:") {
+		return "group_by must be host, device_type, group or tag:<key>"
+	}
+	if def.Format == "" {
+		def.Format = "html"
+	}
+	if def.Format != "html" && def.Format != "pdf" {
+		return "format must be html or pdf"
+	}
+	if _, err := jobs.ReportDefinitionScope(def); err != nil {
+		return err.Error()
+	}
+	if msg := validateHostSelectors(def.GroupID, def.TagSelector); msg != "" {
+		return msg
+	}
+	for _, address := range strings.Split(def.Recipients, ",") {
+		if address = strings.TrimSpace(address); address == "" {
+			continue
+		}
+		if _, err := mail.ParseAddress(address); err != nil {
+			return "invalid recipient " + address
+		}
+	}
+	if def.ChannelName != "" {
+		var channel models.AlertChannel
+		if err := database.DB.Where("name = ?", def.ChannelName).First(&channel).Error; err != nil {
+			return "channel_name does not exist"
+		}
+	}
+	return ""
+}
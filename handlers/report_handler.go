@@ -0,0 +1,68 @@
+package handlers
+
+import (
+	"alerting-app/database"
+	"alerting-app/jobs"
+	"encoding/csv"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// GetUptimeReport computes availability over ?from=&to= (default: the last 30 days).
+// ?group_by=host|device_type|group|tag:<key>, ?host_ids=1,2, ?device_type=, ?group_id=, ?tags=site=ub-hq,
+// ?format=json|csv
+func GetUptimeReport(c *fiber.Ctx) error {
+	db := database.DB
+
+	from, to, err := parseTimeRange(c, 30*24*time.Hour)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	scope := jobs.ReportScope{DeviceType: c.Query("device_type"), TagSelector: c.Query("tags")}
+	if groupID := c.QueryInt("group_id"); groupID > 0 {
+		id := uint(groupID)
+		scope.GroupID = &id
+	}
+	if ids := c.Query("host_ids"); ids != "" {
+		for _, id := range strings.Split(ids, ",") {
+			parsed, err := strconv.ParseUint(strings.TrimSpace(id), 10, 64)
+			if err != nil {
+				return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+					"error": "host_ids must be a comma separated list of ids",
+				})
+			}
+			scope.HostIDs = append(scope.HostIDs, uint(parsed))
+		}
+	}
+
+	report, err := jobs.BuildUptimeReport(db, from, to, c.Query("group_by", "host"), scope)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	if c.Query("format") == "csv" {
+		c.Set(fiber.HeaderContentType, "text/csv; charset=utf-8")
+		c.Set(fiber.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="uptime-%s-%s.csv"`,
+			from.Format("20060102"), to.Format("20060102")))
+
+		writer := csv.NewWriter(c)
+		writer.Write(jobs.UptimeCSVHeader)
+		for _, row := range report.Rows {
+			writer.Write(jobs.UptimeCSVRecord(row))
+		}
+		writer.Write(jobs.UptimeCSVRecord(report.Total))
+		writer.Flush()
+		return writer.Error()
+	}
+
+	return c.Status(fiber.StatusOK).JSON(report)
+}
@@ -0,0 +1,218 @@
+package handlers
+
+import (
+	"alerting-app/database"
+	"alerting-app/jobs"
+	"alerting-app/models"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const maxSeriesPoints = 1000
+
+// seriesPoint is one aggregated bucket of check results
+type seriesPoint struct {
+	Bucket     time.Time `json:"time"`
+	Checks     int64     `json:"checks"`
+	UpRatio    float64   `json:"up_ratio"`
+	AvgLatency float64   `json:"avg_latency_ms"`
+	MinLatency float64   `json:"min_latency_ms"`
+	MaxLatency float64   `json:"max_latency_ms"`
+	P95Latency *float64  `json:"p95_latency_ms,omitempty"`
+}
+
+// seriesRow is what both the raw and the rollup aggregation queries scan into
+type seriesRow struct {
+	Bucket     int64
+	Checks     int64
+	UpCount    int64
+	AvgLatency float64
+	MinLatency float64
+	MaxLatency float64
+	P95Latency *float64
+}
+
+// GetHostResults returns check results of a host aggregated into ?step= buckets between ?from= and ?to=.
+// ?resolution= picks the source (raw, 5m, 1h, 1d); by default the finest one covering the range is used.
+func GetHostResults(c *fiber.Ctx) error {
+	db := database.DB
+
+	var host models.Host
+	if err := db.First(&host, c.Params("id")).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Host not found",
+		})
+	}
+
+	from, to, err := parseTimeRange(c, 24*time.Hour)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	resolution, size, err := pickResolution(c.Query("resolution", "auto"), from, to)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	step, err := parseStep(c.Query("step"), to.Sub(from), size)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	seconds := int64(step / time.Second)
+	var rows []seriesRow
+	if resolution == "raw" {
+		err = db.Model(&models.CheckResult{}).
+			Select("FLOOR(UNIX_TIMESTAMP(checked_at) / ?) * ? AS bucket, COUNT(*) AS checks, "+
+				"SUM(CASE WHEN status = 'up' THEN 1 ELSE 0 END) AS up_count, "+
+				"COALESCE(AVG(CASE WHEN status = 'up' THEN latency_ms END), 0) AS avg_latency, "+
+				"COALESCE(MIN(CASE WHEN status = 'up' THEN latency_ms END), 0) AS min_latency, "+
+				"COALESCE(MAX(CASE WHEN status = 'up' THEN latency_ms END), 0) AS max_latency", seconds, seconds).
+			Where("host_id = ? AND checked_at >= ? AND checked_at < ?", host.ID, from, to).
+			Group("bucket").Order("bucket ASC").Scan(&rows).Error
+	} else {
+		// Rollups are merged with latency weighted by successful checks; p95 is the worst bucket's
+		err = db.Model(&models.CheckRollup{}).
+			Select("FLOOR(UNIX_TIMESTAMP(bucket_start) / ?) * ? AS bucket, SUM(checks) AS checks, SUM(up_count) AS up_count, "+
+				"COALESCE(SUM(avg_latency * up_count) / NULLIF(SUM(up_count), 0), 0) AS avg_latency, "+
+				"COALESCE(MIN(CASE WHEN up_count > 0 THEN min_latency END), 0) AS min_latency, "+
+				"MAX(max_latency) AS max_latency, MAX(p95_latency) AS p95_latency", seconds, seconds).
+			Where("host_id = ? AND resolution = ? AND bucket_start >= ? AND bucket_start < ?", host.ID, resolution, from, to).
+			Group("bucket").Order("bucket ASC").Scan(&rows).Error
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Error fetching check results",
+		})
+	}
+
+	series := make([]seriesPoint, 0, len(rows))
+	for _, row := range rows {
+		if row.Checks == 0 {
+			continue
+		}
+		series = append(series, seriesPoint{
+			Bucket:     time.Unix(row.Bucket, 0),
+			Checks:     row.Checks,
+			UpRatio:    float64(row.UpCount) / float64(row.Checks),
+			AvgLatency: row.AvgLatency,
+			MinLatency: row.MinLatency,
+			MaxLatency: row.MaxLatency,
+			P95Latency: row.P95Latency,
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"host_id":    host.ID,
+		"from":       from,
+		"to":         to,
+		"step":       seconds,
+		"resolution": resolution,
+		"data":       series,
+	})
+}
+
+// pickResolution validates an explicit resolution, or for "auto" picks the finest source
+// that still holds data for the whole range and fits the point limit; parseStep then raises
+// a step finer than the source to the source's size
+func pickResolution(requested string, from, to time.Time) (string, time.Duration, error) {
+	type source struct {
+		name      string
+		size      time.Duration
+		retention time.Duration
+	}
+	sources := []source{{name: "raw", size: time.Minute, retention: jobs.ResultRetention()}}
+	for _, level := range jobs.RollupLevels {
+		sources = append(sources, source{name: level.Name, size: level.Size, retention: level.Retention()})
+	}
+
+	if requested != "auto" {
+		for _, src := range sources {
+			if src.name == requested {
+				return src.name, src.size, nil
+			}
+		}
+		return "", 0, fmt.Errorf("resolution must be auto, raw, 5m, 1h or 1d")
+	}
+
+	span := to.Sub(from)
+	for _, src := range sources {
+		if src.retention > 0 && time.Since(from) > src.retention {
+			continue
+		}
+		if span/src.size > maxSeriesPoints {
+			continue
+		}
+		return src.name, src.size, nil
+	}
+	last := sources[len(sources)-1]
+	return last.name, last.size, nil
+}
+
+// parseTimeRange reads ?from= and ?to= as RFC3339 or unix seconds; to defaults to now
+func parseTimeRange(c *fiber.Ctx, defaultRange time.Duration) (time.Time, time.Time, error) {
+	to := time.Now()
+	if value := c.Query("to"); value != "" {
+		parsed, err := parseTimeParam(value)
+		if err != nil {
+			return to, to, fmt.Errorf("invalid to: %v", err)
+		}
+		to = parsed
+	}
+	from := to.Add(-defaultRange)
+	if value := c.Query("from"); value != "" {
+		parsed, err := parseTimeParam(value)
+		if err != nil {
+			return from, to, fmt.Errorf("invalid from: %v", err)
+		}
+		from = parsed
+	}
+	if !from.Before(to) {
+		return from, to, fmt.Errorf("from must be before to")
+	}
+	return from, to, nil
+}
+
+func parseTimeParam(value string) (time.Time, error) {
+	if seconds, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.Unix(seconds, 0), nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
+
+// parseStep accepts a Go duration ("5m") or seconds; empty picks a step giving about 300 points.
+// The step is never finer than the resolution of the source.
+func parseStep(value string, span, minimum time.Duration) (time.Duration, error) {
+	step := span / 300
+	if value != "" {
+		parsed, err := parseDuration(value)
+		if err != nil {
+			return 0, err
+		}
+		step = parsed
+	}
+	if step < minimum {
+		step = minimum
+	}
+	if span/step > maxSeriesPoints {
+		return 0, fmt.Errorf("step too small for the range, at most %d points are returned", maxSeriesPoints)
+	}
+	return step.Truncate(time.Second), nil
+}
+
+func parseDuration(value string) (time.Duration, error) {
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return time.Duration(seconds) * time.Second, nil
+	}
+	if parsed, err := time.ParseDuration(value); err == nil {
+		return parsed, nil
+	}
+	return 0, fmt.Errorf("invalid step %q", value)
+}
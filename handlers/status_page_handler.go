@@ -0,0 +1,296 @@
+package handlers
+
+import (
+	"alerting-app/database"
+	"alerting-app/jobs"
+	"alerting-app/models"
+	"fmt"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+var slugPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{0,99}$`)
+
+// publicStatusPageTTL is how long a built public page is served before its uptime history is recomputed
+const publicStatusPageTTL = 30 * time.Second
+
+// publicStatusPageCache holds the last built public page per status page ID; anonymous traffic
+// would otherwise scan check results on every request
+var publicStatusPageCache = struct {
+	sync.Mutex
+	entries map[uint]*publicStatusPageEntry
+}{entries: map[uint]*publicStatusPageEntry{}}
+
+// publicStatusPageEntry is locked while it is rebuilt, so concurrent visitors wait for one build
+type publicStatusPageEntry struct {
+	sync.Mutex
+	page    *jobs.PublicStatusPage
+	builtAt time.Time
+}
+
+func GetStatusPages(c *fiber.Ctx) error {
+	db := database.DB
+	var pages []models.StatusPage
+
+	if err := db.Preload("Components", func(db *gorm.DB) *gorm.DB {
+		return db.Order("position ASC")
+	}).Order("slug ASC").Find(&pages).Error; err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	return c.Status(200).JSON(pages)
+}
+
+func CreateStatusPage(c *fiber.Ctx) error {
+	db := database.DB
+
+	page := new(models.StatusPage)
+	if err := c.BodyParser(page); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	page.ID = 0
+	if msg := validateStatusPage(page); msg != "" {
+		return c.Status(400).JSON(fiber.Map{
+			"error": msg,
+		})
+	}
+	for i := range page.Components {
+		page.Components[i].ID = 0
+	}
+
+	if err := db.Create(page).Error; err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	return c.Status(200).JSON(page)
+}
+
+func UpdateStatusPage(c *fiber.Ctx) error {
+	db := database.DB
+
+	var page models.StatusPage
+	if err := db.First(&page, c.Params("id")).Error; err != nil {
+		return c.Status(404).JSON(fiber.Map{
+			"error": "Status page not found",
+		})
+	}
+
+	var update models.StatusPage
+	if err := c.BodyParser(&update); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	update.ID = page.ID
+	if msg := validateStatusPage(&update); msg != "" {
+		return c.Status(400).JSON(fiber.Map{
+			"error": msg,
+		})
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		page.Slug = update.Slug
+		page.Title = update.Title
+		page.Description = update.Description
+		if err := tx.Save(&page).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("page_id = ?", page.ID).Delete(&models.StatusPageComponent{}).Error; err != nil {
+			return err
+		}
+		for i := range update.Components {
+			update.Components[i].ID = 0
+			update.Components[i].PageID = page.ID
+		}
+		if len(update.Components) > 0 {
+			if err := tx.Create(&update.Components).Error; err != nil {
+				return err
+			}
+		}
+		page.Components = update.Components
+		return nil
+	})
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	forgetPublicStatusPage(page.ID)
+	return c.Status(200).JSON(page)
+}
+
+func DeleteStatusPage(c *fiber.Ctx) error {
+	db := database.DB
+
+	var page models.StatusPage
+	if err := db.First(&page, c.Params("id")).Error; err != nil {
+		return c.Status(404).JSON(fiber.Map{
+			"error": "Status page not found",
+		})
+	}
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("page_id = ?", page.ID).Delete(&models.StatusPageComponent{}).Error; err != nil {
+			return err
+		}
+		return tx.Unscoped().Delete(&page).Error
+	})
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	forgetPublicStatusPage(page.ID)
+	return c.Status(200).JSON(fiber.Map{
+		"message": "Status page deleted",
+	})
+}
+
+// GetPublicStatusPage serves the page as HTML; no authentication required
+func GetPublicStatusPage(c *fiber.Ctx) error {
+	public, err := loadPublicStatusPage(c.Params("slug"))
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).SendString("Status page not found")
+	}
+	body, err := jobs.RenderStatusPageHTML(public)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).SendString("Failed to render status page")
+	}
+	c.Set(fiber.HeaderContentType, fiber.MIMETextHTMLCharsetUTF8)
+	return c.Send(body)
+}
+
+// GetPublicStatusPageJSON serves the same data as JSON for embedding elsewhere
+func GetPublicStatusPageJSON(c *fiber.Ctx) error {
+	public, err := loadPublicStatusPage(c.Params("slug"))
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Status page not found",
+		})
+	}
+	return c.Status(fiber.StatusOK).JSON(public)
+}
+
+func loadPublicStatusPage(slug string) (*jobs.PublicStatusPage, error) {
+	db := database.DB
+	var page models.StatusPage
+	if err := db.Preload("Components", func(db *gorm.DB) *gorm.DB {
+		return db.Order("position ASC")
+	}).Where("slug = ?", slug).First(&page).Error; err != nil {
+		return nil, err
+	}
+
+	publicStatusPageCache.Lock()
+	entry, ok := publicStatusPageCache.entries[page.ID]
+	if !ok {
+		entry = &publicStatusPageEntry{}
+		publicStatusPageCache.entries[page.ID] = entry
+	}
+	publicStatusPageCache.Unlock()
+
+	entry.Lock()
+	defer entry.Unlock()
+	if entry.page != nil && time.Since(entry.builtAt) < publicStatusPageTTL {
+		return entry.page, nil
+	}
+	public, err := jobs.BuildStatusPage(db, &page)
+	if err != nil {
+		return nil, err
+	}
+	entry.page, entry.builtAt = public, time.Now()
+	return public, nil
+}
+
+// forgetPublicStatusPage drops the cached public page so an edit shows on the next visit
+func forgetPublicStatusPage(id uint) {
+	publicStatusPageCache.Lock()
+	delete(publicStatusPageCache.entries, id)
+	publicStatusPageCache.Unlock()
+}
+
+// UpdateIncidentPublicNote sets the note shown for the incident on status pages
+func UpdateIncidentPublicNote(c *fiber.Ctx) error {
+	db := database.DB
+
+	var incident models.Incident
+	if err := db.First(&incident, c.Params("id")).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Incident not found",
+		})
+	}
+
+	var body struct {
+		PublicNote string `json:"public_note"`
+	}
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	incident.PublicNote = body.PublicNote
+	if err := db.Model(&incident).Update("public_note", incident.PublicNote).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	return c.Status(fiber.StatusOK).JSON(incident)
+}
+
+func validateStatusPage(page *models.StatusPage) string {
+	db := database.DB
+	if !slugPattern.MatchString(page.Slug) {
+		return "slug must be lowercase letters, digits and dashes"
+	}
+	if page.Title == "" {
+		return "title is required"
+	}
+	var count int64
+	db.Model(&models.StatusPage{}).Where("slug = ? AND id <> ?", page.Slug, page.ID).Count(&count)
+	if count > 0 {
+		return "slug is already in use"
+	}
+
+	for i, component := range page.Components {
+		if component.DisplayName == "" {
+			return fmt.Sprintf("component %d: display_name is required", i+1)
+		}
+		kinds := 0
+		if component.HostID != nil {
+			kinds++
+		}
+		if component.DeviceTypeName != "" {
+			kinds++
+		}
+		if component.GroupID != nil {
+			kinds++
+		}
+		if kinds != 1 {
+			return fmt.Sprintf("component %d: set exactly one of host_id, device_type_name or group_id", i+1)
+		}
+		switch {
+		case component.HostID != nil:
+			var host models.Host
+			if err := db.First(&host, *component.HostID).Error; err != nil {
+				return fmt.Sprintf("component %d: host_id does not exist", i+1)
+			}
+		case component.GroupID != nil:
+			var group models.HostGroup
+			if err := db.First(&group, *component.GroupID).Error; err != nil {
+				return fmt.Sprintf("component %d: group_id does not exist", i+1)
+			}
+		default:
+			var deviceType models.DeviceType
+			if err := db.Where("dev_type = ?", component.DeviceTypeName).First(&deviceType).Error; err != nil {
+				return fmt.Sprintf("component %d: device type does not exist", i+1)
+			}
+		}
+	}
+	return ""
+}
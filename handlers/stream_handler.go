@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"alerting-app/events"
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const streamHeartbeat = 15 * time.Second
+
+// streamFilter keeps the events a client asked for; empty sets match everything
+type streamFilter struct {
+	hostIDs     map[uint]bool
+	deviceTypes map[string]bool
+	types       map[string]bool
+}
+
+func (f streamFilter) match(event events.Envelope) bool {
+	group := strings.SplitN(event.Type, ".", 2)[0]
+	if group == "tick" {
+		return false // scheduler internals
+	}
+	if len(f.types) > 0 && !f.types[event.Type] && !f.types[group] {
+		return false
+	}
+	if len(f.hostIDs) > 0 && !f.hostIDs[event.HostID] {
+		return false
+	}
+	if len(f.deviceTypes) > 0 && !f.deviceTypes[event.DeviceType] {
+		return false
+	}
+	return true
+}
+
+// StreamEvents pushes live events as Server-Sent Events.
+// ?host_id=1,2 ?device_type=a,b ?types=check,state,incident,notification narrow the stream;
+// types also accept full event names such as incident.opened.
+func StreamEvents(c *fiber.Ctx) error {
+	filter := streamFilter{hostIDs: map[uint]bool{}, deviceTypes: map[string]bool{}, types: map[string]bool{}}
+	for _, id := range splitQuery(c.Query("host_id")) {
+		parsed, err := strconv.ParseUint(id, 10, 64)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": fmt.Sprintf("invalid host_id %q", id),
+			})
+		}
+		filter.hostIDs[uint(parsed)] = true
+	}
+	for _, deviceType := range splitQuery(c.Query("device_type")) {
+		filter.deviceTypes[deviceType] = true
+	}
+	for _, eventType := range splitQuery(c.Query("types")) {
+		filter.types[eventType] = true
+	}
+
+	c.Set(fiber.HeaderContentType, "text/event-stream")
+	c.Set(fiber.HeaderCacheControl, "no-cache")
+	c.Set(fiber.HeaderConnection, "keep-alive")
+	c.Set("X-Accel-Buffering", "no") // keep reverse proxies from buffering the stream
+
+	sub := events.Default.Subscribe(256)
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		defer sub.Close()
+		ticker := time.NewTicker(streamHeartbeat)
+		defer ticker.Stop()
+
+		fmt.Fprint(w, "retry: 5000\n\n")
+		if w.Flush() != nil {
+			return
+		}
+		for {
+			select {
+			case event, ok := <-sub.C:
+				if !ok {
+					return
+				}
+				if !filter.match(event) {
+					continue
+				}
+				data, err := json.Marshal(event)
+				if err != nil {
+					continue
+				}
+				fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Type, data)
+			case <-ticker.C:
+				fmt.Fprint(w, ": ping\n\n")
+			}
+			// A failed flush means the client went away
+			if w.Flush() != nil {
+				return
+			}
+		}
+	})
+	return nil
+}
+
+func splitQuery(value string) []string {
+	var parts []string
+	for _, part := range strings.Split(value, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return parts
+}
@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"alerting-app/database"
+	"alerting-app/models"
+	"net/mail"
+	"net/url"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func GetUsers(c *fiber.Ctx) error {
+	db := database.DB
+	var users []models.User
+	if err := db.Find(&users).Error; err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	return c.Status(200).JSON(users)
+}
+
+func GetUserContacts(c *fiber.Ctx) error {
+	db := database.DB
+
+	var user models.User
+	if err := db.First(&user, c.Params("id")).Error; err != nil {
+		return c.Status(404).JSON(fiber.Map{
+			"error": "User not found",
+		})
+	}
+
+	var contacts []models.UserContact
+	if err := db.Where("user_id = ?", user.ID).Order("priority ASC").Find(&contacts).Error; err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	return c.Status(200).JSON(contacts)
+}
+
+func CreateUserContact(c *fiber.Ctx) error {
+	db := database.DB
+
+	var user models.User
+	if err := db.First(&user, c.Params("id")).Error; err != nil {
+		return c.Status(404).JSON(fiber.Map{
+			"error": "User not found",
+		})
+	}
+
+	contact := new(models.UserContact)
+	if err := c.BodyParser(contact); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	contact.ID = 0
+	contact.UserID = user.ID
+	if msg := validateUserContact(contact); msg != "" {
+		return c.Status(400).JSON(fiber.Map{
+			"error": msg,
+		})
+	}
+
+	if err := db.Create(contact).Error; err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	return c.Status(200).JSON(contact)
+}
+
+func DeleteUserContact(c *fiber.Ctx) error {
+	db := database.DB
+
+	var contact models.UserContact
+	if err := db.Where("id = ? AND user_id = ?", c.Params("contactId"), c.Params("id")).First(&contact).Error; err != nil {
+		return c.Status(404).JSON(fiber.Map{
+			"error": "Contact not found",
+		})
+	}
+	if err := db.Delete(&contact).Error; err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	return c.Status(200).JSON(fiber.Map{
+		"message": "Contact deleted",
+	})
+}
+
+func validateUserContact(contact *models.UserContact) string {
+	if contact.Value == "" {
+		return "value is required"
+	}
+	switch contact.Type {
+	case "email":
+		if _, err := mail.ParseAddress(contact.Value); err != nil {
+			return "value is not a valid email address"
+		}
+	case "telegram":
+	case "webhook":
+		if u, err := url.Parse(contact.Value); err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			return "value is not a valid http(s) URL"
+		}
+	default:
+		return "type must be one of email, telegram or webhook"
+	}
+	return ""
+}
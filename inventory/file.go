@@ -0,0 +1,156 @@
+package inventory
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ManagedBy marks the rows owned by the configuration file
+const ManagedBy = "file"
+
+// File is the declarative inventory; every list is matched to the database by name
+type File struct {
+	DeviceTypes        []DeviceTypeSpec        `json:"device_types" yaml:"device_types"`
+	Channels           []ChannelSpec           `json:"channels" yaml:"channels"`
+	Hosts              []HostSpec              `json:"hosts" yaml:"hosts"`
+	MaintenanceWindows []MaintenanceWindowSpec `json:"maintenance_windows" yaml:"maintenance_windows"`
+}
+
+type DeviceTypeSpec struct {
+	Name             string  `json:"name" yaml:"name"`
+	SLATarget        float64 `json:"sla_target" yaml:"sla_target"`
+	EscalationPolicy string  `json:"escalation_policy" yaml:"escalation_policy"` // policy name
+}
+
+type ChannelSpec struct {
+	Name        string `json:"name" yaml:"name"`
+	Provider    string `json:"provider" yaml:"provider"`
+	Config1     string `json:"config1" yaml:"config1"`
+	Config2     string `json:"config2" yaml:"config2"`
+	Config3     string `json:"config3" yaml:"config3"`
+	Config4     string `json:"config4" yaml:"config4"`
+	GroupBy     string `json:"group_by" yaml:"group_by"`
+	GroupWindow int    `json:"group_window" yaml:"group_window"`
+}
+
+// CheckSpec is how a host is probed
+type CheckSpec struct {
+	Method           string  `json:"method" yaml:"method"`
+	Interval         int     `json:"interval" yaml:"interval"`         // minutes, default 1
+	NumOfRetry       *int    `json:"num_of_retry" yaml:"num_of_retry"` // default 3
+	ExpectedResponse *int    `json:"expected_response" yaml:"expected_response"`
+	HttpHeader       *string `json:"http_header" yaml:"http_header"`
+	HttpBody         *string `json:"http_body" yaml:"http_body"`
+}
+
+type HostSpec struct {
+	Name             string            `json:"name" yaml:"name"`
+	IP               string            `json:"ip" yaml:"ip"`
+	Check            CheckSpec         `json:"check" yaml:"check"`
+	Active           *bool             `json:"active" yaml:"active"` // default true
+	DeviceType       string            `json:"device_type" yaml:"device_type"`
+	AlertChannel     string            `json:"alert_channel" yaml:"alert_channel"`
+	Severity         string            `json:"severity" yaml:"severity"` // default major
+	SLATarget        float64           `json:"sla_target" yaml:"sla_target"`
+	Group            string            `json:"group" yaml:"group"`                         // host group name
+	Parent           string            `json:"parent" yaml:"parent"`                       // upstream host name
+	EscalationPolicy string            `json:"escalation_policy" yaml:"escalation_policy"` // policy name
+	Tags             map[string]string `json:"tags" yaml:"tags"`
+}
+
+// MaintenanceWindowSpec scopes like the API: host, device type, group and tags all have to match
+type MaintenanceWindowSpec struct {
+	Name        string    `json:"name" yaml:"name"`
+	Host        string    `json:"host" yaml:"host"` // host name
+	DeviceType  string    `json:"device_type" yaml:"device_type"`
+	Group       string    `json:"group" yaml:"group"`
+	TagSelector string    `json:"tag_selector" yaml:"tag_selector"`
+	StartsAt    time.Time `json:"starts_at" yaml:"starts_at"`
+	EndsAt      time.Time `json:"ends_at" yaml:"ends_at"`
+	Reason      string    `json:"reason" yaml:"reason"`
+}
+
+// envReference matches ${NAME}; a bare $ is left alone so secrets may contain it
+var envReference = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// ReadFile loads a YAML or JSON inventory, expanding ${NAME} environment references.
+// Unknown keys are rejected so typos do not silently drop settings.
+func ReadFile(path string) (*File, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	data = envReference.ReplaceAllFunc(data, func(ref []byte) []byte {
+		return []byte(os.Getenv(string(ref[2 : len(ref)-1])))
+	})
+
+	file := new(File)
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		err = decoder.Decode(file)
+	} else {
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+		err = decoder.Decode(file)
+		if errors.Is(err, io.EOF) {
+			err = nil // an empty file declares an empty inventory
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return file, nil
+}
+
+// check reports structural problems that do not need the database
+func (f *File) check() []string {
+	var errs []string
+	add := func(format string, args ...interface{}) {
+		errs = append(errs, fmt.Sprintf(format, args...))
+	}
+	unique := func(kind string, names []string) {
+		seen := map[string]bool{}
+		for i, name := range names {
+			switch {
+			case strings.TrimSpace(name) == "":
+				add("%s[%d].name: name is required", kind, i)
+			case seen[name]:
+				add("%s[%d].name: duplicate name %q", kind, i, name)
+			}
+			seen[name] = true
+		}
+	}
+
+	names := make([]string, 0, len(f.DeviceTypes))
+	for _, spec := range f.DeviceTypes {
+		names = append(names, spec.Name)
+	}
+	unique("device_types", names)
+	names = names[:0]
+	for _, spec := range f.Channels {
+		names = append(names, spec.Name)
+	}
+	unique("channels", names)
+	names = names[:0]
+	for _, spec := range f.Hosts {
+		names = append(names, spec.Name)
+	}
+	unique("hosts", names)
+	names = names[:0]
+	for _, spec := range f.MaintenanceWindows {
+		names = append(names, spec.Name)
+	}
+	unique("maintenance_windows", names)
+	return errs
+}
@@ -0,0 +1,52 @@
+package inventory
+
+import (
+	"alerting-app/config"
+	"alerting-app/database"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// FilePath is CONFIG_FILE, empty when the inventory is managed through the API only
+func FilePath() string {
+	return config.Config("CONFIG_FILE")
+}
+
+// PruneByDefault reports whether CONFIG_PRUNE=true, used at startup, on SIGHUP and by reloads without ?prune=
+func PruneByDefault() bool {
+	return config.Config("CONFIG_PRUNE") == "true"
+}
+
+// Start applies the configuration file once and again on every SIGHUP
+func Start() {
+	path := FilePath()
+	if path == "" {
+		return
+	}
+	apply("startup", path)
+
+	hangups := make(chan os.Signal, 1)
+	signal.Notify(hangups, syscall.SIGHUP)
+	go func() {
+		for range hangups {
+			apply("SIGHUP", path)
+		}
+	}()
+}
+
+func apply(reason, path string) {
+	result, err := Sync(database.DB, path, PruneByDefault(), false)
+	if err != nil {
+		log.Printf("Config sync (%s) of %s failed: %v", reason, path, err)
+		for _, problem := range result.Errors {
+			log.Printf("  %s", problem)
+		}
+		return
+	}
+	log.Printf("Config sync (%s) of %s: %d changes, %d unchanged", reason, path, len(result.Changes), result.Unchanged)
+	for _, change := range result.Changes {
+		log.Printf("  %s %s %q %v %s", change.Action, change.Kind, change.Name, change.Fields, change.Reason)
+	}
+}
@@ -0,0 +1,652 @@
+package inventory
+
+import (
+	"alerting-app/jobs"
+	"alerting-app/models"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ErrInvalid is returned when the file fails validation; the problems are in Result.Errors
+var ErrInvalid = errors.New("configuration file is invalid")
+
+var errDryRun = errors.New("dry run")
+
+// Change is one difference between the file and the database
+type Change struct {
+	Kind   string   `json:"kind"` // "device_type", "channel", "host" or "maintenance_window"
+	Name   string   `json:"name"`
+	Action string   `json:"action"` // "create", "update", "delete", "unmanage" or "keep"
+	Fields []string `json:"fields,omitempty"`
+	Reason string   `json:"reason,omitempty"`
+}
+
+// Result reports one sync run
+type Result struct {
+	File      string    `json:"file"`
+	DryRun    bool      `json:"dry_run"`
+	Prune     bool      `json:"prune"`
+	StartedAt time.Time `json:"started_at"`
+	Unchanged int       `json:"unchanged"`
+	Changes   []Change  `json:"changes"`
+	Errors    []string  `json:"errors,omitempty"`
+}
+
+var (
+	syncMu   sync.Mutex
+	lastSync *Result
+)
+
+// LastSync returns the result of the last applied sync, nil before the first one
+func LastSync() *Result {
+	syncMu.Lock()
+	defer syncMu.Unlock()
+	return lastSync
+}
+
+// Sync reconciles the database to the file in one transaction.
+// Objects of the file are created, updated and marked as managed; unmanaged objects with the same name are adopted.
+// Managed objects no longer in the file are deleted with prune, otherwise handed back to the API.
+// A dry run reports the same changes and rolls them back.
+func Sync(db *gorm.DB, path string, prune, dryRun bool) (*Result, error) {
+	syncMu.Lock()
+	defer syncMu.Unlock()
+
+	result := &Result{File: path, DryRun: dryRun, Prune: prune, StartedAt: time.Now(), Changes: []Change{}}
+	file, err := ReadFile(path)
+	if err != nil {
+		result.Errors = []string{err.Error()}
+		return result, ErrInvalid
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		s := &syncer{tx: tx, file: file, prune: prune, result: result}
+		if err := s.load(); err != nil {
+			return err
+		}
+		if result.Errors = append(file.check(), s.validate()...); len(result.Errors) > 0 {
+			return ErrInvalid
+		}
+		for _, step := range []func() error{s.syncDeviceTypes, s.syncChannels, s.syncHosts, s.syncMaintenanceWindows} {
+			if err := step(); err != nil {
+				return err
+			}
+		}
+		if dryRun {
+			return errDryRun
+		}
+		return nil
+	})
+	if errors.Is(err, errDryRun) {
+		err = nil
+	}
+	if !dryRun {
+		lastSync = result
+	}
+	return result, err
+}
+
+// syncer holds the lookups of one run
+type syncer struct {
+	tx     *gorm.DB
+	file   *File
+	prune  bool
+	result *Result
+
+	methods     map[string]uint
+	deviceTypes map[string]bool
+	channels    map[string]bool
+	groups      map[string]uint
+	policies    map[string]uint
+	hosts       map[string]uint
+}
+
+func (s *syncer) load() error {
+	s.methods, s.deviceTypes, s.channels = map[string]uint{}, map[string]bool{}, map[string]bool{}
+	s.groups, s.policies, s.hosts = map[string]uint{}, map[string]uint{}, map[string]uint{}
+
+	var methods []models.CheckConfig
+	if err := s.tx.Find(&methods).Error; err != nil {
+		return err
+	}
+	for _, method := range methods {
+		s.methods[method.Method] = method.ID
+	}
+	var deviceTypes []models.DeviceType
+	if err := s.tx.Find(&deviceTypes).Error; err != nil {
+		return err
+	}
+	for _, deviceType := range deviceTypes {
+		s.deviceTypes[deviceType.DevType] = true
+	}
+	var channels []models.AlertChannel
+	if err := s.tx.Find(&channels).Error; err != nil {
+		return err
+	}
+	for _, channel := range channels {
+		s.channels[channel.Name] = true
+	}
+	var groups []models.HostGroup
+	if err := s.tx.Find(&groups).Error; err != nil {
+		return err
+	}
+	for _, group := range groups {
+		s.groups[group.Name] = group.ID
+	}
+	var policies []models.EscalationPolicy
+	if err := s.tx.Find(&policies).Error; err != nil {
+		return err
+	}
+	for _, policy := range policies {
+		s.policies[policy.Name] = policy.ID
+	}
+	var hosts []models.Host
+	if err := s.tx.Select("id", "name").Order("id ASC").Find(&hosts).Error; err != nil {
+		return err
+	}
+	for _, host := range hosts {
+		if _, ok := s.hosts[host.Name]; !ok {
+			s.hosts[host.Name] = host.ID
+		}
+	}
+	return nil
+}
+
+// validate checks the references of the file against the database and the file itself
+func (s *syncer) validate() []string {
+	var errs []string
+	add := func(format string, args ...interface{}) {
+		errs = append(errs, fmt.Sprintf(format, args...))
+	}
+	fileDeviceTypes, fileChannels, fileHosts := map[string]bool{}, map[string]bool{}, map[string]bool{}
+	for _, spec := range s.file.DeviceTypes {
+		fileDeviceTypes[spec.Name] = true
+	}
+	for _, spec := range s.file.Channels {
+		fileChannels[spec.Name] = true
+	}
+	for _, spec := range s.file.Hosts {
+		fileHosts[spec.Name] = true
+	}
+
+	for i, spec := range s.file.DeviceTypes {
+		if spec.SLATarget < 0 || spec.SLATarget > 100 {
+			add("device_types[%d].sla_target: must be a percentage between 0 and 100", i)
+		}
+		if _, ok := s.policies[spec.EscalationPolicy]; spec.EscalationPolicy != "" && !ok {
+			add("device_types[%d].escalation_policy: unknown escalation policy %q", i, spec.EscalationPolicy)
+		}
+	}
+
+	for i, spec := range s.file.Channels {
+		provider := spec.Provider
+		if provider == "" {
+			provider = spec.Name
+		}
+		if !jobs.IsKnownProvider(provider) {
+			add("channels[%d].provider: unknown provider %q", i, provider)
+		}
+		if strings.TrimSpace(spec.Config1) == "" {
+			add("channels[%d].config1: config1 is required", i)
+		}
+		switch spec.GroupBy {
+		case "", "none", "channel", "device_type", "parent", "group":
+		default:
+			if !strings.HasPrefix(spec.GroupBy, "tag:") || spec.GroupBy == "tag:" {
+				add("channels[%d].group_by: must be none, channel, device_type, parent, group or tag:<key>", i)
+			}
+		}
+		if spec.GroupWindow < 0 {
+			add("channels[%d].group_window: cannot be negative", i)
+		}
+	}
+
+	for i, spec := range s.file.Hosts {
+		if strings.TrimSpace(spec.IP) == "" {
+			add("hosts[%d].ip: ip is required", i)
+		} else if err := jobs.ValidateCheckTarget(spec.Check.Method, spec.IP); err != nil {
+			add("hosts[%d].ip: %v", i, err)
+		}
+		if _, ok := s.methods[spec.Check.Method]; !ok {
+			add("hosts[%d].check.method: unknown check method %q", i, spec.Check.Method)
+		}
+		if spec.Check.Interval < 0 {
+			add("hosts[%d].check.interval: must be at least 1 minute", i)
+		}
+		if spec.Check.NumOfRetry != nil && *spec.Check.NumOfRetry < 0 {
+			add("hosts[%d].check.num_of_retry: cannot be negative", i)
+		}
+		if code := spec.Check.ExpectedResponse; code != nil && (*code < 100 || *code > 599) {
+			add("hosts[%d].check.expected_response: must be an HTTP status code", i)
+		}
+		if err := jobs.ValidateHeaders(spec.Check.HttpHeader); err != nil {
+			add("hosts[%d].check.http_header: %v", i, err)
+		}
+		if spec.DeviceType != "" && !s.deviceTypes[spec.DeviceType] && !fileDeviceTypes[spec.DeviceType] {
+			add("hosts[%d].device_type: unknown device type %q", i, spec.DeviceType)
+		}
+		if spec.AlertChannel != "" && !s.channels[spec.AlertChannel] && !fileChannels[spec.AlertChannel] {
+			add("hosts[%d].alert_channel: unknown alert channel %q", i, spec.AlertChannel)
+		}
+		if spec.Severity != "" {
+			if _, ok := models.SeverityLevels[spec.Severity]; !ok {
+				add("hosts[%d].severity: must be one of info, minor, major or critical", i)
+			}
+		}
+		if spec.SLATarget < 0 || spec.SLATarget > 100 {
+			add("hosts[%d].sla_target: must be a percentage between 0 and 100", i)
+		}
+		if _, ok := s.groups[spec.Group]; spec.Group != "" && !ok {
+			add("hosts[%d].group: unknown group %q", i, spec.Group)
+		}
+		if _, ok := s.hosts[spec.Parent]; spec.Parent != "" && !ok && !fileHosts[spec.Parent] {
+			add("hosts[%d].parent: unknown host %q", i, spec.Parent)
+		}
+		if spec.Parent != "" && spec.Parent == spec.Name {
+			add("hosts[%d].parent: a host cannot be its own parent", i)
+		}
+		if _, ok := s.policies[spec.EscalationPolicy]; spec.EscalationPolicy != "" && !ok {
+			add("hosts[%d].escalation_policy: unknown escalation policy %q", i, spec.EscalationPolicy)
+		}
+		for key, value := range spec.Tags {
+			if key == "" || strings.ContainsAny(key, "=,") || strings.Contains(value, ",") {
+				add("hosts[%d].tags: keys cannot be empty or contain '=' or ',', values cannot contain ','", i)
+				break
+			}
+		}
+	}
+
+	for i, spec := range s.file.MaintenanceWindows {
+		if spec.StartsAt.IsZero() || spec.EndsAt.IsZero() {
+			add("maintenance_windows[%d]: starts_at and ends_at are required", i)
+		} else if !spec.EndsAt.After(spec.StartsAt) {
+			add("maintenance_windows[%d].ends_at: must be after starts_at", i)
+		}
+		if _, ok := s.hosts[spec.Host]; spec.Host != "" && !ok && !fileHosts[spec.Host] {
+			add("maintenance_windows[%d].host: unknown host %q", i, spec.Host)
+		}
+		if spec.DeviceType != "" && !s.deviceTypes[spec.DeviceType] && !fileDeviceTypes[spec.DeviceType] {
+			add("maintenance_windows[%d].device_type: unknown device type %q", i, spec.DeviceType)
+		}
+		if _, ok := s.groups[spec.Group]; spec.Group != "" && !ok {
+			add("maintenance_windows[%d].group: unknown group %q", i, spec.Group)
+		}
+		if _, err := jobs.ParseTagSelector(spec.TagSelector); err != nil {
+			add("maintenance_windows[%d].tag_selector: %v", i, err)
+		}
+	}
+	return errs
+}
+
+// record adds a change for an upserted row, or counts it as unchanged
+func (s *syncer) record(kind, name string, created bool, before, after map[string]interface{}) bool {
+	if created {
+		s.result.Changes = append(s.result.Changes, Change{Kind: kind, Name: name, Action: "create"})
+		return true
+	}
+	var fields []string
+	for key, value := range after {
+		if !reflect.DeepEqual(before[key], value) {
+			fields = append(fields, key)
+		}
+	}
+	if len(fields) == 0 {
+		s.result.Unchanged++
+		return false
+	}
+	sort.Strings(fields)
+	s.result.Changes = append(s.result.Changes, Change{Kind: kind, Name: name, Action: "update", Fields: fields})
+	return true
+}
+
+// release deletes a managed row that left the file, or hands it back to the API when not pruning
+func (s *syncer) release(kind, name string, row interface{}, refs int64, hard bool) error {
+	if !s.prune {
+		s.result.Changes = append(s.result.Changes, Change{Kind: kind, Name: name, Action: "unmanage"})
+		return s.tx.Model(row).Update("managed_by", "").Error
+	}
+	if refs > 0 {
+		s.result.Changes = append(s.result.Changes, Change{Kind: kind, Name: name, Action: "keep",
+			Reason: fmt.Sprintf("still referenced %d times", refs)})
+		return nil
+	}
+	s.result.Changes = append(s.result.Changes, Change{Kind: kind, Name: name, Action: "delete"})
+	query := s.tx
+	if hard {
+		// Hard delete so the unique name can be reused
+		query = query.Unscoped()
+	}
+	return query.Delete(row).Error
+}
+
+func optionalID(ids map[string]uint, name string) *uint {
+	id, ok := ids[name]
+	if !ok {
+		return nil
+	}
+	return &id
+}
+
+func deviceTypeFields(deviceType *models.DeviceType) map[string]interface{} {
+	return map[string]interface{}{
+		"sla_target":           deviceType.SLATarget,
+		"escalation_policy_id": deviceType.EscalationPolicyID,
+		"managed_by":           deviceType.ManagedBy,
+	}
+}
+
+func (s *syncer) syncDeviceTypes() error {
+	var existing []models.DeviceType
+	if err := s.tx.Find(&existing).Error; err != nil {
+		return err
+	}
+	byName := map[string]*models.DeviceType{}
+	for i := range existing {
+		byName[existing[i].DevType] = &existing[i]
+	}
+
+	wanted := map[string]bool{}
+	for _, spec := range s.file.DeviceTypes {
+		wanted[spec.Name] = true
+		deviceType, found := byName[spec.Name]
+		if !found {
+			deviceType = &models.DeviceType{DevType: spec.Name}
+		}
+		before := deviceTypeFields(deviceType)
+		deviceType.SLATarget = spec.SLATarget
+		deviceType.EscalationPolicyID = optionalID(s.policies, spec.EscalationPolicy)
+		deviceType.ManagedBy = ManagedBy
+		if s.record("device_type", spec.Name, !found, before, deviceTypeFields(deviceType)) {
+			if err := s.tx.Save(deviceType).Error; err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, deviceType := range existing {
+		if deviceType.ManagedBy != ManagedBy || wanted[deviceType.DevType] {
+			continue
+		}
+		var refs int64
+		s.tx.Model(&models.Host{}).Where("device_type_name = ?", deviceType.DevType).Count(&refs)
+		if err := s.release("device_type", deviceType.DevType, &deviceType, refs, true); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func channelFields(channel *models.AlertChannel) map[string]interface{} {
+	return map[string]interface{}{
+		"provider":     channel.Provider,
+		"config1":      channel.Config1,
+		"config2":      channel.Config2,
+		"config3":      channel.Config3,
+		"config4":      channel.Config4,
+		"group_by":     channel.GroupBy,
+		"group_window": channel.GroupWindow,
+		"managed_by":   channel.ManagedBy,
+	}
+}
+
+func (s *syncer) syncChannels() error {
+	var existing []models.AlertChannel
+	if err := s.tx.Find(&existing).Error; err != nil {
+		return err
+	}
+	byName := map[string]*models.AlertChannel{}
+	for i := range existing {
+		byName[existing[i].Name] = &existing[i]
+	}
+
+	wanted := map[string]bool{}
+	for _, spec := range s.file.Channels {
+		wanted[spec.Name] = true
+		channel, found := byName[spec.Name]
+		if !found {
+			channel = &models.AlertChannel{Name: spec.Name}
+		}
+		before := channelFields(channel)
+		channel.Provider = spec.Provider
+		if channel.Provider == "" {
+			channel.Provider = spec.Name
+		}
+		channel.Config1, channel.Config2, channel.Config3, channel.Config4 = spec.Config1, spec.Config2, spec.Config3, spec.Config4
+		channel.GroupBy = spec.GroupBy
+		if channel.GroupBy == "" {
+			channel.GroupBy = "none"
+		}
+		channel.GroupWindow = spec.GroupWindow
+		channel.ManagedBy = ManagedBy
+		if s.record("channel", spec.Name, !found, before, channelFields(channel)) {
+			if err := s.tx.Save(channel).Error; err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, channel := range existing {
+		if channel.ManagedBy != ManagedBy || wanted[channel.Name] {
+			continue
+		}
+		refs := jobs.ChannelReferences(s.tx, channel.Name)
+		if err := s.release("channel", channel.Name, &channel, refs, true); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func hostFields(host *models.Host) map[string]interface{} {
+	tags := map[string]string{}
+	for _, tag := range host.Tags {
+		tags[tag.Key] = tag.Value
+	}
+	return map[string]interface{}{
+		"ip":                   host.IP,
+		"method":               host.MethodID,
+		"interval":             host.Interval,
+		"num_of_retry":         host.NumOfRetry,
+		"expected_response":    host.ExpectedResponse,
+		"http_header":          host.HttpHeader,
+		"http_body":            host.HttpBody,
+		"is_active":            host.IsActive,
+		"device_type":          host.DeviceTypeName,
+		"alert_channel":        host.AlertChannelName,
+		"severity":             host.Severity,
+		"sla_target":           host.SLATarget,
+		"group_id":             host.GroupID,
+		"parent_id":            host.ParentID,
+		"escalation_policy_id": host.EscalationPolicyID,
+		"tags":                 tags,
+		"managed_by":           host.ManagedBy,
+	}
+}
+
+func (s *syncer) syncHosts() error {
+	var existing []models.Host
+	if err := s.tx.Preload("Tags").Order("id ASC").Find(&existing).Error; err != nil {
+		return err
+	}
+	// A managed host wins over an unmanaged one with the same name
+	byName := map[string]*models.Host{}
+	for i := range existing {
+		host := &existing[i]
+		if current, ok := byName[host.Name]; !ok || (current.ManagedBy != ManagedBy && host.ManagedBy == ManagedBy) {
+			byName[host.Name] = host
+		}
+	}
+
+	// Create first so parents declared later in the file resolve
+	wanted := map[string]bool{}
+	hosts := make([]*models.Host, len(s.file.Hosts))
+	befores := make([]map[string]interface{}, len(s.file.Hosts))
+	for i, spec := range s.file.Hosts {
+		wanted[spec.Name] = true
+		host, found := byName[spec.Name]
+		if !found {
+			host = &models.Host{Name: spec.Name, IP: spec.IP, MethodID: s.methods[spec.Check.Method], ManagedBy: ManagedBy}
+			if err := s.tx.Omit("Tags").Create(host).Error; err != nil {
+				return err
+			}
+			s.result.Changes = append(s.result.Changes, Change{Kind: "host", Name: spec.Name, Action: "create"})
+		} else {
+			befores[i] = hostFields(host)
+		}
+		s.hosts[spec.Name] = host.ID
+		hosts[i] = host
+	}
+
+	for i, spec := range s.file.Hosts {
+		host := hosts[i]
+		host.IP = spec.IP
+		host.MethodID = s.methods[spec.Check.Method]
+		host.Interval = spec.Check.Interval
+		if host.Interval == 0 {
+			host.Interval = 1
+		}
+		host.NumOfRetry = 3
+		if spec.Check.NumOfRetry != nil {
+			host.NumOfRetry = *spec.Check.NumOfRetry
+		}
+		host.ExpectedResponse = spec.Check.ExpectedResponse
+		host.HttpHeader = spec.Check.HttpHeader
+		host.HttpBody = spec.Check.HttpBody
+		host.IsActive = spec.Active == nil || *spec.Active
+		host.DeviceTypeName = spec.DeviceType
+		host.AlertChannelName = spec.AlertChannel
+		host.Severity = spec.Severity
+		if host.Severity == "" {
+			host.Severity = "major"
+		}
+		host.SLATarget = spec.SLATarget
+		host.GroupID = optionalID(s.groups, spec.Group)
+		host.ParentID = optionalID(s.hosts, spec.Parent)
+		host.EscalationPolicyID = optionalID(s.policies, spec.EscalationPolicy)
+		host.ManagedBy = ManagedBy
+		host.Tags = make([]models.HostTag, 0, len(spec.Tags))
+		for key, value := range spec.Tags {
+			host.Tags = append(host.Tags, models.HostTag{HostID: host.ID, Key: key, Value: value})
+		}
+
+		columns := hostColumns(host)
+		if befores[i] != nil {
+			if !s.record("host", spec.Name, false, befores[i], hostFields(host)) {
+				continue
+			}
+			columns["version"] = models.NextHostVersion
+		}
+		// Only the configured columns: the scheduler keeps writing check state while the sync runs
+		if err := s.tx.Model(&models.Host{}).Where("id = ?", host.ID).Updates(columns).Error; err != nil {
+			return err
+		}
+		if err := s.tx.Where("host_id = ?", host.ID).Delete(&models.HostTag{}).Error; err != nil {
+			return err
+		}
+		if len(host.Tags) > 0 {
+			if err := s.tx.Create(&host.Tags).Error; err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, host := range existing {
+		if host.ManagedBy != ManagedBy || wanted[host.Name] {
+			continue
+		}
+		// Soft delete, like the API, so the history keeps its host
+		if err := s.release("host", host.Name, &host, 0, false); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// hostColumns are the columns the configuration file sets on a host
+func hostColumns(host *models.Host) map[string]interface{} {
+	return map[string]interface{}{
+		"ip":                   host.IP,
+		"method_id":            host.MethodID,
+		"interval":             host.Interval,
+		"num_of_retry":         host.NumOfRetry,
+		"expected_response":    host.ExpectedResponse,
+		"http_header":          host.HttpHeader,
+		"http_body":            host.HttpBody,
+		"is_active":            host.IsActive,
+		"device_type_name":     host.DeviceTypeName,
+		"alert_channel_name":   host.AlertChannelName,
+		"severity":             host.Severity,
+		"sla_target":           host.SLATarget,
+		"group_id":             host.GroupID,
+		"parent_id":            host.ParentID,
+		"escalation_policy_id": host.EscalationPolicyID,
+		"managed_by":           host.ManagedBy,
+	}
+}
+
+func windowFields(window *models.MaintenanceWindow) map[string]interface{} {
+	return map[string]interface{}{
+		"host_id":      window.HostID,
+		"device_type":  window.DeviceTypeName,
+		"group_id":     window.GroupID,
+		"tag_selector": window.TagSelector,
+		"starts_at":    window.StartsAt.Unix(),
+		"ends_at":      window.EndsAt.Unix(),
+		"reason":       window.Reason,
+		"managed_by":   window.ManagedBy,
+	}
+}
+
+func (s *syncer) syncMaintenanceWindows() error {
+	var existing []models.MaintenanceWindow
+	if err := s.tx.Order("id ASC").Find(&existing).Error; err != nil {
+		return err
+	}
+	byName := map[string]*models.MaintenanceWindow{}
+	for i := range existing {
+		window := &existing[i]
+		if current, ok := byName[window.Name]; !ok || (current.ManagedBy != ManagedBy && window.ManagedBy == ManagedBy) {
+			byName[window.Name] = window
+		}
+	}
+
+	wanted := map[string]bool{}
+	for _, spec := range s.file.MaintenanceWindows {
+		wanted[spec.Name] = true
+		window, found := byName[spec.Name]
+		if !found {
+			window = &models.MaintenanceWindow{Name: spec.Name}
+		}
+		before := windowFields(window)
+		window.HostID = optionalID(s.hosts, spec.Host)
+		window.DeviceTypeName = spec.DeviceType
+		window.GroupID = optionalID(s.groups, spec.Group)
+		window.TagSelector = spec.TagSelector
+		window.StartsAt = spec.StartsAt
+		window.EndsAt = spec.EndsAt
+		window.Reason = spec.Reason
+		window.ManagedBy = ManagedBy
+		if s.record("maintenance_window", spec.Name, !found, before, windowFields(window)) {
+			if err := s.tx.Save(window).Error; err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, window := range existing {
+		if window.ManagedBy != ManagedBy || wanted[window.Name] {
+			continue
+		}
+		if err := s.release("maintenance_window", window.Name, &window, 0, false); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,50 @@
+package jobs
+
+import (
+	"alerting-app/models"
+
+	"gorm.io/gorm"
+)
+
+// channelReferrers are the columns that name an alert channel.
+// Deleted hosts count too, so restoring one does not bring back a dangling channel.
+var channelReferrers = []struct {
+	model    interface{}
+	column   string
+	unscoped bool
+}{
+	{&models.Host{}, "alert_channel_name", true},
+	{&models.EscalationStep{}, "channel_name", false},
+	{&models.AlertRoute{}, "channel_name", false},
+	{&models.QuietHours{}, "channel_name", false},
+	{&models.ReportDefinition{}, "channel_name", false},
+}
+
+// ChannelReferences counts the rows that point at the channel
+func ChannelReferences(db *gorm.DB, name string) int64 {
+	var total int64
+	for _, ref := range channelReferrers {
+		query := db
+		if ref.unscoped {
+			query = query.Unscoped()
+		}
+		var count int64
+		query.Model(ref.model).Where(ref.column+" = ?", name).Count(&count)
+		total += count
+	}
+	return total
+}
+
+// ReassignChannel moves every reference from one channel to another
+func ReassignChannel(tx *gorm.DB, from, to string) error {
+	for _, ref := range channelReferrers {
+		query := tx
+		if ref.unscoped {
+			query = query.Unscoped()
+		}
+		if err := query.Model(ref.model).Where(ref.column+" = ?", from).Update(ref.column, to).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,151 @@
+package jobs
+
+import (
+	"alerting-app/models"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Slack, Teams and Discord channels all use Config1 as the incoming webhook URL.
+
+// slackNotifier posts a Block Kit message wrapped in a colored attachment
+func slackNotifier(channel models.AlertChannel, notification *models.Notification) (string, error) {
+	details := buildAlertDetails(notification)
+
+	blocks := []map[string]interface{}{
+		{
+			"type": "header",
+			"text": map[string]interface{}{"type": "plain_text", "text": details.Title},
+		},
+		{
+			"type": "section",
+			"text": map[string]interface{}{"type": "mrkdwn", "text": details.Message},
+		},
+	}
+	if len(details.Fields) > 0 {
+		var fields []map[string]interface{}
+		for _, field := range details.Fields {
+			fields = append(fields, map[string]interface{}{"type": "mrkdwn", "text": fmt.Sprintf("*%s*\n%s", field[0], field[1])})
+		}
+		blocks = append(blocks, map[string]interface{}{"type": "section", "fields": fields})
+	}
+	if details.DashboardURL != "" {
+		blocks = append(blocks, map[string]interface{}{
+			"type": "actions",
+			"elements": []map[string]interface{}{{
+				"type": "button",
+				"text": map[string]interface{}{"type": "plain_text", "text": "Open dashboard"},
+				"url":  details.DashboardURL,
+			}},
+		})
+	}
+
+	payload := map[string]interface{}{
+		"text": details.Title,
+		"attachments": []map[string]interface{}{{
+			"color":  "#" + details.Color,
+			"blocks": blocks,
+		}},
+	}
+	return postWebhookJSON(channel, payload)
+}
+
+// teamsNotifier posts an Adaptive Card
+func teamsNotifier(channel models.AlertChannel, notification *models.Notification) (string, error) {
+	details := buildAlertDetails(notification)
+
+	titleColor := "Accent"
+	switch details.Status {
+	case "down":
+		titleColor = "Attention"
+	case "up":
+		titleColor = "Good"
+	case "escalation", "reminder":
+		titleColor = "Warning"
+	}
+
+	body := []map[string]interface{}{
+		{"type": "TextBlock", "text": details.Title, "weight": "Bolder", "size": "Medium", "color": titleColor, "wrap": true},
+		{"type": "TextBlock", "text": details.Message, "wrap": true},
+	}
+	if len(details.Fields) > 0 {
+		var facts []map[string]string
+		for _, field := range details.Fields {
+			facts = append(facts, map[string]string{"title": field[0], "value": field[1]})
+		}
+		body = append(body, map[string]interface{}{"type": "FactSet", "facts": facts})
+	}
+
+	card := map[string]interface{}{
+		"$schema": "http://adaptivecards.io/schemas/adaptive-card.json",
+		"type":    "AdaptiveCard",
+		"version": "1.4",
+		"body":    body,
+	}
+	if details.DashboardURL != "" {
+		card["actions"] = []map[string]interface{}{
+			{"type": "Action.OpenUrl", "title": "Open dashboard", "url": details.DashboardURL},
+		}
+	}
+
+	payload := map[string]interface{}{
+		"type": "message",
+		"attachments": []map[string]interface{}{{
+			"contentType": "application/vnd.microsoft.card.adaptive",
+			"content":     card,
+		}},
+	}
+	return postWebhookJSON(channel, payload)
+}
+
+// discordNotifier posts an embed colored by the alert status
+func discordNotifier(channel models.AlertChannel, notification *models.Notification) (string, error) {
+	details := buildAlertDetails(notification)
+
+	color, _ := strconv.ParseInt(details.Color, 16, 64)
+	embed := map[string]interface{}{
+		"title":       details.Title,
+		"description": details.Message,
+		"color":       color,
+		"timestamp":   time.Now().Format(time.RFC3339),
+	}
+	if details.DashboardURL != "" {
+		embed["url"] = details.DashboardURL
+	}
+	if len(details.Fields) > 0 {
+		var fields []map[string]interface{}
+		for _, field := range details.Fields {
+			fields = append(fields, map[string]interface{}{"name": field[0], "value": field[1], "inline": true})
+		}
+		embed["fields"] = fields
+	}
+
+	payload := map[string]interface{}{
+		"embeds": []map[string]interface{}{embed},
+	}
+	return postWebhookJSON(channel, payload)
+}
+
+// postWebhookJSON sends the payload to the channel's webhook URL (Config1)
+func postWebhookJSON(channel models.AlertChannel, payload interface{}) (string, error) {
+	if channel.Config1 == "" {
+		return "", fmt.Errorf("%w: channel %q has no webhook URL", errUnsupportedChannel, channel.Name)
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal payload: %v", err)
+	}
+
+	req, err := http.NewRequest("POST", channel.Config1, bytes.NewBuffer(body))
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	return doProviderRequest(req)
+}
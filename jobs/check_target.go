@@ -0,0 +1,39 @@
+package jobs
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"regexp"
+)
+
+var hostnamePattern = regexp.MustCompile(`^[a-zA-Z0-9]([a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?(\.[a-zA-Z0-9]([a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?)*\.?$`)
+
+// ValidateCheckTarget checks the host address against its check method:
+// ping needs an IP address or hostname, the HTTP checks an absolute http(s) URL
+func ValidateCheckTarget(method, target string) error {
+	switch method {
+	case "ping":
+		if net.ParseIP(target) != nil {
+			return nil
+		}
+		if len(target) > 253 || !hostnamePattern.MatchString(target) {
+			return errors.New("must be an IP address or hostname for ping checks")
+		}
+	case "http_get", "http_post":
+		u, err := url.Parse(target)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("must be an http:// or https:// URL for %s checks", method)
+		}
+	}
+	return nil
+}
+
+// ValidateHeaders checks that the header setting parses the way the HTTP checks read it
+func ValidateHeaders(header *string) error {
+	if _, err := parseHeaders(header); err != nil {
+		return errors.New(`must be a JSON object of strings, e.g. {"Authorization": "Bearer ..."}`)
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+package jobs
+
+import (
+	"alerting-app/config"
+	"alerting-app/database"
+	"alerting-app/models"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-co-op/gocron"
+)
+
+// localScheduler runs jobs that are configured in local wall-clock time
+var localScheduler = gocron.NewScheduler(time.Local)
+
+// scheduleDailyDigest enables the digest when DIGEST_CHANNEL is set; DIGEST_TIME defaults to 08:00
+func scheduleDailyDigest() {
+	channel := config.Config("DIGEST_CHANNEL")
+	if channel == "" {
+		return
+	}
+	at := config.Config("DIGEST_TIME")
+	if at == "" {
+		at = "08:00"
+	}
+
+	if _, err := localScheduler.Every(1).Day().At(at).Do(sendDailyDigest, channel); err != nil {
+		log.Printf("Failed to schedule daily digest at %s: %v", at, err)
+		return
+	}
+	fmt.Printf("Daily digest scheduled at %s to channel %s\n", at, channel)
+}
+
+// sendDailyDigest summarises the incidents of the last 24 hours
+func sendDailyDigest(channelName string) {
+	db := database.DB
+	end := time.Now()
+	start := end.Add(-24 * time.Hour)
+
+	var hostCount int64
+	db.Model(&models.Host{}).Where("is_active = ?", true).Count(&hostCount)
+
+	var incidents []models.Incident
+	if err := db.Where("opened_at < ? AND (resolved_at IS NULL OR resolved_at > ?)", end, start).
+		Order("opened_at ASC").Find(&incidents).Error; err != nil {
+		log.Println("Failed to load incidents for digest:", err)
+		return
+	}
+
+	downtime := map[string]time.Duration{}
+	var total time.Duration
+	opened, open := 0, 0
+	for _, incident := range incidents {
+		from, to := incident.OpenedAt, end
+		if from.Before(start) {
+			from = start
+		} else {
+			opened++
+		}
+		if incident.ResolvedAt != nil && incident.ResolvedAt.Before(end) {
+			to = *incident.ResolvedAt
+		} else {
+			open++
+		}
+		downtime[incident.HostName] += to.Sub(from)
+		total += to.Sub(from)
+	}
+
+	uptime := 100.0
+	if hostCount > 0 {
+		uptime = 100 * (1 - total.Hours()/(24*float64(hostCount)))
+	}
+
+	var text strings.Builder
+	fmt.Fprintf(&text, "Daily digest %s - %s\n", start.Format("2006-01-02 15:04"), end.Format("2006-01-02 15:04"))
+	fmt.Fprintf(&text, "Active hosts: %d, fleet uptime: %.2f%%\n", hostCount, uptime)
+	fmt.Fprintf(&text, "Incidents opened: %d, still open: %d\n", opened, open)
+
+	names := make([]string, 0, len(downtime))
+	for name := range downtime {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool { return downtime[names[i]] > downtime[names[j]] })
+	for _, name := range names {
+		fmt.Fprintf(&text, "- %s: down %s\n", name, downtime[name].Round(time.Minute))
+	}
+
+	queueNotification(db, &models.Notification{
+		HostName:      "digest",
+		ChannelName:   channelName,
+		Event:         "digest",
+		Message:       strings.TrimRight(text.String(), "\n"),
+		Status:        models.NotificationPending,
+		MaxAttempts:   defaultMaxAttempts,
+		NextAttemptAt: time.Now(),
+	})
+}
@@ -0,0 +1,237 @@
+package jobs
+
+import (
+	"alerting-app/database"
+	"alerting-app/events"
+	"alerting-app/models"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// openIncident records a new incident for a host that just went down
+func openIncident(db *gorm.DB, host *models.Host) {
+	var existing models.Incident
+	err := db.Where("host_id = ? AND status = ?", host.ID, models.IncidentOpen).First(&existing).Error
+	if err == nil {
+		return
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		log.Printf("Failed to look up open incident for host %s: %v", host.Name, err)
+		return
+	}
+
+	now := time.Now()
+	incident := models.Incident{
+		HostID:             host.ID,
+		HostName:           host.Name,
+		Status:             models.IncidentOpen,
+		OpenedAt:           now,
+		EscalationPolicyID: resolveEscalationPolicyID(db, host),
+		LastNotifiedAt:     &now,
+	}
+	if err := db.Create(&incident).Error; err != nil {
+		log.Printf("Failed to open incident for host %s: %v", host.Name, err)
+		return
+	}
+	events.Publish(events.IncidentOpened{Incident: incident, DeviceType: host.DeviceTypeName})
+}
+
+// openIncidentFor returns the host's open incident, or nil
+func openIncidentFor(db *gorm.DB, hostID uint) *models.Incident {
+	var incident models.Incident
+	if err := db.Where("host_id = ? AND status = ?", hostID, models.IncidentOpen).First(&incident).Error; err != nil {
+		return nil
+	}
+	return &incident
+}
+
+// resolveIncident closes the host's open incident, which stops its escalation
+func resolveIncident(db *gorm.DB, host *models.Host) {
+	var incidents []models.Incident
+	if err := db.Where("host_id = ? AND status = ?", host.ID, models.IncidentOpen).Find(&incidents).Error; err != nil {
+		log.Printf("Failed to load open incidents for host %s: %v", host.Name, err)
+		return
+	}
+
+	now := time.Now()
+	for i := range incidents {
+		incident := &incidents[i]
+		if err := db.Model(incident).
+			Updates(map[string]interface{}{"status": models.IncidentResolved, "resolved_at": now}).Error; err != nil {
+			log.Printf("Failed to resolve incident for host %s: %v", host.Name, err)
+			continue
+		}
+		incident.Status = models.IncidentResolved
+		incident.ResolvedAt = &now
+		events.Publish(events.IncidentResolved{Incident: *incident, DeviceType: host.DeviceTypeName})
+	}
+}
+
+// resolveEscalationPolicyID prefers the host's own policy over its device type's
+func resolveEscalationPolicyID(db *gorm.DB, host *models.Host) *uint {
+	if host.EscalationPolicyID != nil {
+		return host.EscalationPolicyID
+	}
+	if host.DeviceTypeName == "" {
+		return nil
+	}
+
+	var devType models.DeviceType
+	if err := db.Where("dev_type = ?", host.DeviceTypeName).First(&devType).Error; err != nil {
+		return nil
+	}
+	return devType.EscalationPolicyID
+}
+
+// AcknowledgeIncident stops further escalation of an open incident
+func AcknowledgeIncident(incident *models.Incident, by string) error {
+	if incident.Status != models.IncidentOpen {
+		return fmt.Errorf("incident %d is already %s", incident.ID, incident.Status)
+	}
+	if incident.AcknowledgedAt != nil {
+		return nil
+	}
+
+	db := database.DB
+	now := time.Now()
+	result := db.Model(&models.Incident{}).
+		Where("id = ? AND status = ? AND acknowledged_at IS NULL", incident.ID, models.IncidentOpen).
+		Updates(map[string]interface{}{"acknowledged_at": now, "acknowledged_by": by})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		// Resolved or acknowledged since it was loaded
+		if err := db.First(incident, incident.ID).Error; err != nil {
+			return err
+		}
+		if incident.Status != models.IncidentOpen {
+			return fmt.Errorf("incident %d is already %s", incident.ID, incident.Status)
+		}
+		return nil
+	}
+	incident.AcknowledgedAt = &now
+	incident.AcknowledgedBy = by
+	events.Publish(events.IncidentAcknowledged{Incident: *incident, DeviceType: hostDeviceType(incident.HostID)})
+	return nil
+}
+
+// evaluateEscalations notifies the next steps of every unacknowledged open incident
+func evaluateEscalations() {
+	db := database.DB
+
+	var incidents []models.Incident
+	if err := db.Where("status = ? AND acknowledged_at IS NULL AND escalation_policy_id IS NOT NULL", models.IncidentOpen).
+		Find(&incidents).Error; err != nil {
+		log.Println("Failed to load open incidents:", err)
+		return
+	}
+
+	now := time.Now()
+	for i := range incidents {
+		escalateIncident(db, &incidents[i], now)
+	}
+}
+
+func escalateIncident(db *gorm.DB, incident *models.Incident, now time.Time) {
+	var policy models.EscalationPolicy
+	if err := db.Preload("Steps").First(&policy, *incident.EscalationPolicyID).Error; err != nil {
+		log.Printf("Failed to load escalation policy for incident %d: %v", incident.ID, err)
+		return
+	}
+	sort.Slice(policy.Steps, func(i, j int) bool { return policy.Steps[i].Position < policy.Steps[j].Position })
+
+	var host models.Host
+	if err := db.Unscoped().First(&host, incident.HostID).Error; err != nil {
+		log.Printf("Failed to load host for incident %d: %v", incident.ID, err)
+		return
+	}
+	if hostMuted(&host) || inMaintenance(db, &host, now) {
+		return
+	}
+
+	type pendingStep struct {
+		step           models.EscalationStep
+		event, message string
+	}
+	var pending []pendingStep
+	level := incident.EscalationLevel
+	downFor := now.Sub(incident.OpenedAt)
+
+	// Walk forward through every step whose delay has elapsed
+	for level < len(policy.Steps) {
+		step := policy.Steps[level]
+		if downFor < time.Duration(step.DelayMinutes)*time.Minute {
+			break
+		}
+		message := fmt.Sprintf("ESCALATION (level %d): %s IP is %s has been down for %s and is not acknowledged",
+			level+1, host.Name, host.IP, downFor.Round(time.Minute))
+		pending = append(pending, pendingStep{step, "escalation", message})
+		level++
+	}
+
+	// Remind every channel reached so far while the host stays down
+	if len(pending) == 0 && policy.RepeatWhileDown && policy.RepeatInterval > 0 && level > 0 &&
+		incident.LastNotifiedAt != nil && now.Sub(*incident.LastNotifiedAt) >= time.Duration(policy.RepeatInterval)*time.Minute {
+		message := fmt.Sprintf("REMINDER: %s IP is %s is still down (%s) and is not acknowledged",
+			host.Name, host.IP, downFor.Round(time.Minute))
+		notified := map[string]bool{}
+		for _, step := range policy.Steps[:level] {
+			key := stepTargetKey(step)
+			if notified[key] {
+				continue
+			}
+			notified[key] = true
+			pending = append(pending, pendingStep{step, "reminder", message})
+		}
+	}
+	if len(pending) == 0 {
+		return
+	}
+
+	// Claim the escalation first: an incident resolved or acknowledged since it was loaded is left alone
+	result := db.Model(&models.Incident{}).
+		Where("id = ? AND status = ? AND acknowledged_at IS NULL", incident.ID, models.IncidentOpen).
+		Updates(map[string]interface{}{"escalation_level": level, "last_notified_at": now})
+	if result.Error != nil {
+		log.Printf("Failed to update incident %d: %v", incident.ID, result.Error)
+		return
+	}
+	if result.RowsAffected == 0 {
+		return
+	}
+	incident.EscalationLevel = level
+	incident.LastNotifiedAt = &now
+	for _, p := range pending {
+		notifyStep(db, &host, incident, p.step, p.event, p.message)
+	}
+}
+
+// notifyStep queues the message for every target configured on the step
+func notifyStep(db *gorm.DB, host *models.Host, incident *models.Incident, step models.EscalationStep, event, message string) {
+	if step.ChannelName != "" {
+		enqueueIncidentNotification(db, host, incident, step.ChannelName, event, message)
+	}
+	if step.UserID != nil {
+		notifyUser(db, host, incident, *step.UserID, event, message)
+	}
+	if step.ScheduleID != nil {
+		notifyOnCall(db, host, incident, *step.ScheduleID, event, message)
+	}
+}
+
+func stepTargetKey(step models.EscalationStep) string {
+	key := "channel:" + step.ChannelName
+	if step.UserID != nil {
+		key += fmt.Sprintf("|user:%d", *step.UserID)
+	}
+	if step.ScheduleID != nil {
+		key += fmt.Sprintf("|schedule:%d", *step.ScheduleID)
+	}
+	return key
+}
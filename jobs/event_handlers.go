@@ -0,0 +1,97 @@
+package jobs
+
+import (
+	"alerting-app/database"
+	"alerting-app/events"
+	"alerting-app/models"
+	"sync"
+	"time"
+)
+
+var startHandlersOnce sync.Once
+
+// startEventHandlers subscribes history, alerting, results and metrics to the bus.
+// Each runs on its own queue, so a slow notifier or database write never stalls the checks.
+func startEventHandlers() {
+	startHandlersOnce.Do(func() {
+		events.Handle("results", handleResultEvent)
+		events.Handle("history", handleHistoryEvent)
+		events.Handle("alerts", handleAlertEvent)
+		events.Handle("metrics", handleMetricsEvent)
+	})
+}
+
+// handleResultEvent stores the raw result of every check
+func handleResultEvent(envelope events.Envelope) {
+	if check, ok := envelope.Data.(events.CheckCompleted); ok {
+		recordCheckResult(database.DB, check)
+	}
+}
+
+// handleHistoryEvent writes a history row for every state change
+func handleHistoryEvent(envelope events.Envelope) {
+	if change, ok := envelope.Data.(events.StateChanged); ok {
+		host := change.Host
+		writeHostHistory(database.DB, &host, change.To, change.To == "down")
+	}
+}
+
+// handleAlertEvent opens and resolves incidents and queues the down and up alerts.
+// The tick events hold grouped alerts until every host of the tick has been seen.
+func handleAlertEvent(envelope events.Envelope) {
+	db := database.DB
+	switch event := envelope.Data.(type) {
+	case events.TickStarted:
+		beginAlertTick()
+	case events.TickFinished:
+		endAlertTick(db)
+	case events.StateChanged:
+		host := event.Host
+		if event.To == "down" {
+			openIncident(db, &host)
+			sendAlert(&host, true)
+		} else {
+			sendAlert(&host, false)
+			resolveIncident(db, &host)
+		}
+	}
+}
+
+// handleMetricsEvent feeds the Prometheus metrics
+func handleMetricsEvent(envelope events.Envelope) {
+	switch event := envelope.Data.(type) {
+	case events.CheckCompleted:
+		observeCheck(event)
+	case events.TickFinished:
+		observeTick(event)
+	case events.NotificationSent:
+		observeNotification(event.Notification)
+	}
+}
+
+func checkCompleted(host *models.Host, outcome checkOutcome, elapsed time.Duration) events.CheckCompleted {
+	status := "up"
+	if outcome.Down {
+		status = "down"
+	}
+	return events.CheckCompleted{
+		Host:       *host,
+		CheckedAt:  host.LastCheckedDate,
+		Status:     status,
+		Method:     outcome.Method,
+		Latency:    outcome.Latency,
+		LatencyMs:  float64(outcome.Latency) / float64(time.Millisecond),
+		StatusCode: outcome.StatusCode,
+		Error:      outcome.Err,
+		Duration:   elapsed,
+	}
+}
+
+// hostDeviceType looks up the device type of a host for incident and notification events
+func hostDeviceType(hostID uint) string {
+	var host models.Host
+	if err := database.DB.Select("device_type_name").First(&host, hostID).Error; err != nil {
+		return ""
+	}
+	return host.DeviceTypeName
+}
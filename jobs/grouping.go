@@ -0,0 +1,230 @@
+package jobs
+
+import (
+	"alerting-app/models"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// tickHoldTimeout bounds how long grouped alerts wait for a running check tick to finish
+const tickHoldTimeout = 10 * time.Minute
+
+// alertTick holds grouped alerts back while a check tick is running,
+// so every alert produced by the tick can be merged into one message.
+var alertTick struct {
+	sync.Mutex
+	active bool
+	held   []uint
+}
+
+func beginAlertTick() {
+	alertTick.Lock()
+	defer alertTick.Unlock()
+	alertTick.active = true
+	alertTick.held = nil
+}
+
+// endAlertTick releases the alerts held during the tick; their group window starts now
+func endAlertTick(db *gorm.DB) {
+	alertTick.Lock()
+	held := alertTick.held
+	alertTick.active = false
+	alertTick.held = nil
+	alertTick.Unlock()
+
+	if len(held) == 0 {
+		return
+	}
+	var notifications []models.Notification
+	if err := db.Where("id IN ? AND status = ? AND deferred = ?", held, models.NotificationPending, false).Find(&notifications).Error; err != nil {
+		log.Println("Failed to release grouped notifications:", err)
+		return
+	}
+	windows := map[string]time.Duration{}
+	for _, notification := range notifications {
+		window, ok := windows[notification.ChannelName]
+		if !ok {
+			var channel models.AlertChannel
+			if err := db.Where("name = ?", notification.ChannelName).First(&channel).Error; err == nil {
+				window = time.Duration(channel.GroupWindow) * time.Second
+			}
+			windows[notification.ChannelName] = window
+		}
+		db.Model(&models.Notification{}).Where("id = ?", notification.ID).Update("next_attempt_at", time.Now().Add(window))
+	}
+}
+
+// applyAlertGrouping tags the notification with its channel's group key and delays it by the group window
+func applyAlertGrouping(db *gorm.DB, host *models.Host, notification *models.Notification) {
+	var channel models.AlertChannel
+	if err := db.Where("name = ?", notification.ChannelName).First(&channel).Error; err != nil {
+		return
+	}
+
+	key := alertGroupKey(db, host, channel.GroupBy)
+	if key == "" {
+		return
+	}
+	notification.GroupKey = key
+	notification.NextAttemptAt = time.Now().Add(time.Duration(channel.GroupWindow) * time.Second)
+
+	alertTick.Lock()
+	if alertTick.active {
+		notification.NextAttemptAt = time.Now().Add(tickHoldTimeout)
+	}
+	alertTick.Unlock()
+}
+
+// holdForTick remembers a queued grouped alert until the running tick ends
+func holdForTick(notification *models.Notification) {
+	if notification.GroupKey == "" || notification.ID == 0 || notification.Deferred {
+		return
+	}
+	alertTick.Lock()
+	defer alertTick.Unlock()
+	if alertTick.active {
+		alertTick.held = append(alertTick.held, notification.ID)
+	}
+}
+
+// alertGroupKey returns "" when the channel does not group alerts
+func alertGroupKey(db *gorm.DB, host *models.Host, groupBy string) string {
+	if tagKey, ok := strings.CutPrefix(groupBy, "tag:"); ok && tagKey != "" {
+		// Hosts without the tag are grouped together under an empty value
+		for _, tag := range hostTags(db, host.ID) {
+			if tag.Key == tagKey {
+				return "tag:" + tagKey + "=" + tag.Value
+			}
+		}
+		return "tag:" + tagKey + "="
+	}
+	switch groupBy {
+	case "channel":
+		return "channel"
+	case "device_type":
+		return "device_type:" + host.DeviceTypeName
+	case "parent":
+		// A parent and its children share a key, so a dead switch reports once
+		if host.ParentID != nil {
+			return fmt.Sprintf("parent:%d", *host.ParentID)
+		}
+		return fmt.Sprintf("parent:%d", host.ID)
+	case "group":
+		if host.GroupID != nil {
+			return fmt.Sprintf("group:%d", *host.GroupID)
+		}
+		return "group:none"
+	default:
+		return ""
+	}
+}
+
+// mergeNotificationGroups replaces every due group of alerts with a single summary notification
+func mergeNotificationGroups(db *gorm.DB) {
+	var due []models.Notification
+	if err := db.Where("status = ? AND group_key <> '' AND attempts = 0 AND next_attempt_at <= ?", models.NotificationPending, time.Now()).
+		Find(&due).Error; err != nil {
+		log.Println("Failed to load grouped notifications:", err)
+		return
+	}
+
+	seen := map[string]bool{}
+	for _, leader := range due {
+		// Quiet hours batches mix events, alert groups are per event
+		event := leader.Event
+		if leader.GroupKey == quietGroupKey {
+			event = ""
+		}
+		id := strings.Join([]string{leader.ChannelName, leader.Recipient, leader.GroupKey, event}, "|")
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+
+		query := db.Where("status = ? AND attempts = 0 AND channel_name = ? AND recipient = ? AND group_key = ?",
+			models.NotificationPending, leader.ChannelName, leader.Recipient, leader.GroupKey)
+		if event != "" {
+			query = query.Where("event = ?", event)
+		}
+		var members []models.Notification
+		if err := query.Order("created_at ASC").Find(&members).Error; err != nil {
+			log.Println("Failed to load notification group:", err)
+			continue
+		}
+		if len(members) < 2 {
+			continue
+		}
+
+		if err := db.Transaction(func(tx *gorm.DB) error {
+			summary := groupSummary(leader, members)
+			if err := tx.Create(summary).Error; err != nil {
+				return err
+			}
+			ids := make([]uint, len(members))
+			for i, member := range members {
+				ids[i] = member.ID
+			}
+			return tx.Model(&models.Notification{}).Where("id IN ?", ids).
+				Updates(map[string]interface{}{"status": models.NotificationGrouped, "grouped_into_id": summary.ID}).Error
+		}); err != nil {
+			log.Println("Failed to merge notification group:", err)
+			continue
+		}
+		log.Printf("Merged %d %s alerts for %s into one message", len(members), leader.Event, leader.ChannelName)
+	}
+}
+
+func groupSummary(leader models.Notification, members []models.Notification) *models.Notification {
+	if leader.GroupKey == quietGroupKey {
+		return quietSummary(leader, members)
+	}
+
+	state := strings.ToUpper(leader.Event)
+	label := strings.TrimPrefix(leader.GroupKey, "channel")
+	if label != "" {
+		label = " (" + strings.Replace(label, ":", " ", 1) + ")"
+	}
+
+	var text strings.Builder
+	fmt.Fprintf(&text, "%d hosts are %s%s:\n", len(members), state, label)
+	for _, member := range members {
+		fmt.Fprintf(&text, "- %s\n", member.Message)
+	}
+
+	return &models.Notification{
+		HostName:      fmt.Sprintf("%d hosts", len(members)),
+		ChannelName:   leader.ChannelName,
+		Recipient:     leader.Recipient,
+		Event:         leader.Event,
+		Message:       strings.TrimRight(text.String(), "\n"),
+		Status:        models.NotificationPending,
+		MaxAttempts:   leader.MaxAttempts,
+		NextAttemptAt: time.Now(),
+	}
+}
+
+// quietSummary lists everything deferred during quiet hours in one message
+func quietSummary(leader models.Notification, members []models.Notification) *models.Notification {
+	var text strings.Builder
+	fmt.Fprintf(&text, "%d notifications deferred during quiet hours:\n", len(members))
+	for _, member := range members {
+		fmt.Fprintf(&text, "- [%s] %s (%s)\n", member.CreatedAt.Format("15:04"), member.Message, member.Severity)
+	}
+
+	return &models.Notification{
+		HostName:      fmt.Sprintf("%d hosts", len(members)),
+		ChannelName:   leader.ChannelName,
+		Recipient:     leader.Recipient,
+		UserID:        leader.UserID,
+		Event:         "batch",
+		Message:       strings.TrimRight(text.String(), "\n"),
+		Status:        models.NotificationPending,
+		MaxAttempts:   leader.MaxAttempts,
+		NextAttemptAt: time.Now(),
+	}
+}
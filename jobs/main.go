@@ -2,16 +2,19 @@ package jobs
 
 import (
 	"alerting-app/database"
+	"alerting-app/events"
 	"alerting-app/models"
 	"bytes"
 	"encoding/json"
-	"errors"
 	"fmt"
 	"io"
 	"log"
 	"net/http"
 	"os/exec"
+	"regexp"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/go-co-op/gocron"
@@ -20,11 +23,25 @@ import (
 
 var cronChecker = gocron.NewScheduler(time.UTC)
 
+// hostCheckLocks holds a *sync.Mutex per host ID, so the scheduler and /check never check one host at once
+var hostCheckLocks sync.Map
+
 // RunCron starts the cron job to check hosts every minute
 func RunCron() {
 	fmt.Println("Starting cron jobs...")
 	cronChecker.Every(1).Minute().Do(checkHostsInDB)
+	cronChecker.Every(15).Seconds().SingletonMode().Do(dispatchNotifications)
+	cronChecker.Every(30).Seconds().SingletonMode().Do(evaluateEscalations)
+	cronChecker.Every(5).Minutes().SingletonMode().Do(rollupCheckResults)
+	cronChecker.Every(1).Hour().SingletonMode().Do(purgeCheckResults)
+	cronChecker.Every(10).Minutes().SingletonMode().Do(runDueReports)
+	startEventHandlers()
 	cronChecker.StartAsync()
+
+	scheduleDailyDigest()
+	localScheduler.StartAsync()
+
+	StartTelegramBot()
 }
 
 // Fetch and check hosts from the database
@@ -51,16 +68,36 @@ func checkHostsInDB() {
 
 	fmt.Println("Hosts to check:", len(hostsToCheck))
 
-	for _, host := range hostsToCheck {
-		down := checkHostStatus(&host, db)
-		host.LastCheckedDate = time.Now()
-		db.Save(&host)
-		if down {
-			handleHostDown(&host, db)
-		} else {
-			handleHostUp(&host, db)
+	started := time.Now()
+	events.Publish(events.TickStarted{})
+	for i := range hostsToCheck {
+		runHostCheck(db, &hostsToCheck[i])
+	}
+	events.Publish(events.TickFinished{Started: started, Duration: time.Since(started), Active: len(hosts), Checked: len(hostsToCheck)})
+}
+
+// runHostCheck checks one host, applies the resulting state and publishes what happened;
+// history, alerts and metrics follow from the published events. It reports whether the host is down
+func runHostCheck(db *gorm.DB, host *models.Host) bool {
+	lock, _ := hostCheckLocks.LoadOrStore(host.ID, &sync.Mutex{})
+	lock.(*sync.Mutex).Lock()
+	defer lock.(*sync.Mutex).Unlock()
+	// Another check may have finished while this one waited; start from the state it saved
+	db.Select("is_pending", "retry_count", "alert_status", "last_checked_date", "last_alert", "last_normal").First(host, host.ID)
+
+	started := time.Now()
+	outcome := checkHostStatus(host, db)
+	host.LastCheckedDate = time.Now()
+	events.Publish(checkCompleted(host, outcome, time.Since(started)))
+
+	if outcome.Down {
+		if handleHostDown(host, db) {
+			events.Publish(events.StateChanged{Host: *host, From: "up", To: "down"})
 		}
+	} else if handleHostUp(host, db) {
+		events.Publish(events.StateChanged{Host: *host, From: "down", To: "up"})
 	}
+	return outcome.Down
 }
 
 // Determines if a host should be checked based on its interval
@@ -70,43 +107,49 @@ func shouldCheckHost(host models.Host, now time.Time) bool {
 	return host.LastCheckedDate.IsZero() || now.Sub(host.LastCheckedDate) > interval
 }
 
+// checkOutcome is the result of a single check
+type checkOutcome struct {
+	Down       bool
+	Latency    time.Duration
+	StatusCode int
+	Err        string
+	Method     string
+}
+
 // Checks host status based on its check method
-func checkHostStatus(host *models.Host, db *gorm.DB) bool {
+func checkHostStatus(host *models.Host, db *gorm.DB) checkOutcome {
 	var checkMethod models.CheckConfig
 	if err := db.First(&checkMethod, host.MethodID).Error; err != nil {
 		log.Println("Failed to fetch check method for host:", host.Name, err)
-		return false
+		return checkOutcome{Err: "check method not found"}
 	}
 	fmt.Println("Checking host", host.Name, "with method", checkMethod.Method)
 
-	resultChan := make(chan bool)
+	resultChan := make(chan checkOutcome)
 
 	switch checkMethod.Method {
 	case "ping":
 		go func() {
-			resultChan <- !pingHost(host.IP)
+			resultChan <- pingHost(host.IP)
 		}()
 	case "http_get":
 		go func() {
 			headers, err := parseHeaders(host.HttpHeader)
 			if err != nil {
 				log.Printf("Error parsing headers for host %s: %v", host.Name, err)
-				resultChan <- false
+				resultChan <- checkOutcome{Err: err.Error()}
 				return
 			}
 
-			success := httpGetHost(host.IP, headers, getExpectedResponse(host))
-			resultChan <- success
+			resultChan <- httpGetHost(host.IP, headers, getExpectedResponse(host))
 		}()
 	case "http_post":
 		go func() {
-			var headers map[string]string
-			if host.HttpHeader != nil {
-				if err := json.Unmarshal([]byte(*host.HttpHeader), &headers); err != nil {
-					fmt.Println("Error parsing headers:", err)
-					resultChan <- false
-					return
-				}
+			headers, err := parseHeaders(host.HttpHeader)
+			if err != nil {
+				fmt.Println("Error parsing headers:", err)
+				resultChan <- checkOutcome{Err: err.Error()}
+				return
 			}
 
 			var body string
@@ -114,113 +157,122 @@ func checkHostStatus(host *models.Host, db *gorm.DB) bool {
 				body = *host.HttpBody
 			}
 
-			resultChan <- !httpPostHost(host.IP, body, headers, *host.ExpectedResponse)
+			resultChan <- httpPostHost(host.IP, body, headers, getExpectedResponse(host))
 		}()
 	default:
 		log.Printf("Unknown check method for host %s: %s", host.Name, checkMethod.Method)
-		return false
+		return checkOutcome{Err: "unknown check method " + checkMethod.Method, Method: checkMethod.Method}
 	}
 
 	// Wait for the result from the goroutine
-	return <-resultChan
+	outcome := <-resultChan
+	outcome.Method = checkMethod.Method
+	return outcome
 }
 
-// Runs a ping command and returns true if the host is reachable
-func pingHost(ip string) bool {
+var pingTimePattern = regexp.MustCompile(`time[=<]([0-9.]+) ?ms`)
+
+// Runs a ping command; the host is up if any of two echoes is answered
+func pingHost(ip string) checkOutcome {
 	successCount := 0
+	var latency time.Duration
+	var lastErr string
 	for i := 0; i < 2; i++ {
+		start := time.Now()
 		cmd := exec.Command("ping", "-c", "1", ip)
-		if err := cmd.Run(); err == nil {
-			successCount++
+		output, err := cmd.CombinedOutput()
+		if err != nil {
+			lastErr = strings.TrimSpace(string(output))
+			if lastErr == "" {
+				lastErr = err.Error()
+			}
+			continue
+		}
+		successCount++
+		// Prefer the round trip reported by ping over the process run time
+		elapsed := time.Since(start)
+		if match := pingTimePattern.FindSubmatch(output); match != nil {
+			if ms, err := strconv.ParseFloat(string(match[1]), 64); err == nil {
+				elapsed = time.Duration(ms * float64(time.Millisecond))
+			}
+		}
+		if latency == 0 || elapsed < latency {
+			latency = elapsed
 		}
 	}
 	fmt.Println("Ping success count:", successCount, ip)
 
-	return successCount > 0
-}
-
-// Performs an HTTP GET request and returns true if the host responds with 200
-func httpGetHost(url string, headers map[string]string, resp_code int) bool {
-	// Create HTTP client with timeout
-	client := &http.Client{
-		Timeout: 20 * time.Second,
+	if successCount == 0 {
+		return checkOutcome{Down: true, Err: lastErr}
 	}
+	return checkOutcome{Latency: latency}
+}
 
+// Performs an HTTP GET request; the host is up if it answers with the expected status code
+func httpGetHost(url string, headers map[string]string, resp_code int) checkOutcome {
 	// Create new request
 	req, err := http.NewRequest("GET", url, nil)
 	if err != nil {
 		fmt.Println("Error creating request:", err)
-		return false
-	}
-
-	// Add custom headers
-	for key, value := range headers {
-		req.Header.Set(key, value)
+		return checkOutcome{Down: true, Err: err.Error()}
 	}
+	return doHTTPCheck(req, headers, resp_code)
+}
 
-	// Make the request
-	resp, err := client.Do(req)
-	if err != nil {
-		fmt.Println("Error making request:", err)
-		return false
-	}
-	defer resp.Body.Close()
-
-	// Read and print response body
-	respBody, err := io.ReadAll(resp.Body)
+// Performs an HTTP POST request; the host is up if it answers with the expected status code
+func httpPostHost(url, body string, headers map[string]string, resp_code int) checkOutcome {
+	// Create new request
+	req, err := http.NewRequest("POST", url, strings.NewReader(body))
 	if err != nil {
-		fmt.Println("Error reading response body:", err)
-		return false
+		fmt.Println("Error creating request:", err)
+		return checkOutcome{Down: true, Err: err.Error()}
 	}
-
-	fmt.Println("Status Code:", resp.StatusCode)
-	fmt.Println("Response Body:", string(respBody))
-	fmt.Println("Response Headers:", resp.Header)
-
-	return resp.StatusCode == resp_code
+	return doHTTPCheck(req, headers, resp_code)
 }
 
-// Performs an HTTP POST request and returns true if the host responds with 200
-func httpPostHost(url, body string, headers map[string]string, resp_code int) bool {
+func doHTTPCheck(req *http.Request, headers map[string]string, resp_code int) checkOutcome {
 	// Create HTTP client with timeout
 	client := &http.Client{
 		Timeout: 20 * time.Second,
 	}
 
-	// Create new request
-	req, err := http.NewRequest("POST", url, strings.NewReader(body))
-	if err != nil {
-		fmt.Println("Error creating request:", err)
-		return false
-	}
-
 	// Add custom headers
 	for key, value := range headers {
 		req.Header.Set(key, value)
 	}
 
 	// Make the request
+	start := time.Now()
 	resp, err := client.Do(req)
 	if err != nil {
 		fmt.Println("Error making request:", err)
-		return false
+		return checkOutcome{Down: true, Latency: time.Since(start), Err: err.Error()}
 	}
 	defer resp.Body.Close()
 
 	// Read and print response body
 	respBody, err := io.ReadAll(resp.Body)
+	latency := time.Since(start)
 	if err != nil {
 		fmt.Println("Error reading response body:", err)
-		return false
+		return checkOutcome{Down: true, Latency: latency, StatusCode: resp.StatusCode, Err: err.Error()}
 	}
 
 	fmt.Println("Status Code:", resp.StatusCode)
 	fmt.Println("Response Body:", string(respBody))
 	fmt.Println("Response Headers:", resp.Header)
 
-	return resp.StatusCode == resp_code
+	outcome := checkOutcome{Latency: latency, StatusCode: resp.StatusCode}
+	if resp.StatusCode != resp_code {
+		outcome.Down = true
+		outcome.Err = fmt.Sprintf("expected status %d, got %d", resp_code, resp.StatusCode)
+	}
+	return outcome
 }
-func handleHostDown(host *models.Host, db *gorm.DB) {
+
+// handleHostDown counts a failed check; it reports whether the host has just been alerted as down
+func handleHostDown(host *models.Host, db *gorm.DB) bool {
+	alerted := false
 	host.IsPending = true
 	host.RetryCount++
 	host.LastCheckedDate = time.Now()
@@ -233,12 +285,15 @@ func handleHostDown(host *models.Host, db *gorm.DB) {
 		host.AlertStatus = true
 		host.LastAlert = time.Now().Format("2006-01-02 15:04:05")
 		host.IsPending = false
-		writeHostHistory(db, host, "down", true)
+		alerted = true
 	}
-	db.Save(host)
+	saveHostState(db, host)
+	return alerted
 }
 
-func handleHostUp(host *models.Host, db *gorm.DB) {
+// handleHostUp resets the host after a successful check; it reports whether the host has just recovered
+func handleHostUp(host *models.Host, db *gorm.DB) bool {
+	recovered := host.AlertStatus
 	host.LastCheckedDate = time.Now()
 	if host.AlertStatus {
 		// Calculate downtime in hours if the host was down before it was marked up
@@ -258,14 +313,27 @@ func handleHostUp(host *models.Host, db *gorm.DB) {
 		host.LastNormal = time.Now().Format("2006-01-02 15:04:05")
 
 		fmt.Printf("Host %s is back up\n", host.Name)
-		writeHostHistory(db, host, "up", false)
 	}
 
 	// New change: if the host is checked and is up, reset `IsPending`
 	if host.IsPending && !host.AlertStatus {
 		host.IsPending = false
 	}
-	db.Save(host)
+	saveHostState(db, host)
+	return recovered
+}
+
+// saveHostState writes only the columns a check changes, so an edit made while the check ran survives
+// and the host keeps its version
+func saveHostState(db *gorm.DB, host *models.Host) {
+	db.Model(host).UpdateColumns(map[string]interface{}{
+		"is_pending":        host.IsPending,
+		"retry_count":       host.RetryCount,
+		"alert_status":      host.AlertStatus,
+		"last_checked_date": host.LastCheckedDate,
+		"last_alert":        host.LastAlert,
+		"last_normal":       host.LastNormal,
+	})
 }
 
 // Time since the last alert (in hours)
@@ -325,53 +393,60 @@ func writeHostHistory(db *gorm.DB, host *models.Host, status string, alertStatus
 	}
 
 	db.Create(&history)
-	sendAlert(host, alertStatus)
 }
 
+// sendAlert queues the alert on the host's channel and on every channel an alert route adds
 func sendAlert(host *models.Host, alertStatus bool) {
-	if host.AlertChannelName == "telegram" {
-		db := database.DB
-		var channel models.AlertChannel
-
-		if err := db.Where("name = ?", host.AlertChannelName).First(&channel).Error; err != nil {
-			if errors.Is(err, gorm.ErrRecordNotFound) {
-				fmt.Println("Record not found")
-			} else {
-				fmt.Printf("Error querying the database: %v\n", err)
-			}
+	db := database.DB
+	channels := []string{host.AlertChannelName}
+	seen := map[string]bool{host.AlertChannelName: true}
+	for _, channel := range routedChannels(db, host) {
+		if !seen[channel] {
+			seen[channel] = true
+			channels = append(channels, channel)
 		}
-		if alertStatus {
-			log.Println("Alerted for host telegram :", host.Name)
-			sendTelegramAlert(host.Name+" IP is "+host.IP+" is Down :(", channel.Config1, channel.Config2, channel.Config3)
-
-		} else {
-			log.Println("Recovered for host telegram :", host.Name)
-			sendTelegramAlert(host.Name+" IP is "+host.IP+" is UP :)", channel.Config1, channel.Config2, channel.Config3)
+	}
 
+	if alertStatus {
+		log.Println("Alert queued for host :", host.Name)
+		incident := openIncidentFor(db, host.ID)
+		for _, channel := range channels {
+			enqueueNotification(db, host, incident, channel, "down", host.Name+" IP is "+host.IP+" is Down :(")
+		}
+	} else {
+		log.Println("Recovery queued for host :", host.Name)
+		for _, channel := range channels {
+			enqueueNotification(db, host, nil, channel, "up", host.Name+" IP is "+host.IP+" is UP :)")
 		}
 	}
 }
 
-func sendTelegramAlert(message, apiurl, tkn, chat_id string) error {
-	// Create the API URL
-	url := fmt.Sprintf("%s%s/sendMessage", apiurl, tkn)
-
-	// Set the parameters
-	params := map[string]interface{}{
+func sendTelegramAlert(message, apiurl, tkn, chat_id string) (string, error) {
+	return telegramCall(apiurl, tkn, "sendMessage", map[string]interface{}{
 		"chat_id": chat_id,
 		"text":    message,
-	}
+	})
+}
+
+// telegramCall invokes a Bot API method and returns the raw response body
+func telegramCall(apiurl, tkn, method string, params map[string]interface{}) (string, error) {
+	return telegramCallTimeout(apiurl, tkn, method, params, 10*time.Second)
+}
+
+func telegramCallTimeout(apiurl, tkn, method string, params map[string]interface{}, timeout time.Duration) (string, error) {
+	// Create the API URL
+	url := fmt.Sprintf("%s%s/%s", apiurl, tkn, method)
 
 	// Marshal the parameters into JSON
 	body, err := json.Marshal(params)
 	if err != nil {
-		return fmt.Errorf("failed to marshal parameters: %v", err)
+		return "", fmt.Errorf("failed to marshal parameters: %v", err)
 	}
 
 	// Send the request
 	req, err := http.NewRequest("POST", url, bytes.NewBuffer(body))
 	if err != nil {
-		return fmt.Errorf("failed to create request: %v", err)
+		return "", fmt.Errorf("failed to create request: %v", err)
 	}
 
 	// Set the content-type header to JSON
@@ -379,25 +454,27 @@ func sendTelegramAlert(message, apiurl, tkn, chat_id string) error {
 
 	// Create an HTTP client with a timeout
 	client := &http.Client{
-		Timeout: 10 * time.Second,
+		Timeout: timeout,
 	}
 
 	// Perform the HTTP request
 	resp, err := client.Do(req)
 	if err != nil {
-		return fmt.Errorf("failed to send request: %v", err)
+		return "", fmt.Errorf("failed to send request: %v", err)
 	}
 	defer resp.Body.Close()
 
+	respBody, _ := io.ReadAll(resp.Body)
+
 	// Check for the response status
 	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+		return string(respBody), fmt.Errorf("unexpected status code: %d", resp.StatusCode)
 	}
 
-	return nil
+	return string(respBody), nil
 }
 func parseHeaders(headerStr *string) (map[string]string, error) {
-	if headerStr == nil {
+	if headerStr == nil || strings.TrimSpace(*headerStr) == "" {
 		return make(map[string]string), nil
 	}
 
@@ -0,0 +1,107 @@
+package jobs
+
+import (
+	"alerting-app/models"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// interval is a half-open time range [Start, End)
+type interval struct {
+	Start time.Time
+	End   time.Time
+}
+
+// inMaintenance reports whether a maintenance window covers the host right now
+func inMaintenance(db *gorm.DB, host *models.Host, at time.Time) bool {
+	var windows []models.MaintenanceWindow
+	maintenanceFor(db, host).Where("starts_at <= ? AND ends_at > ?", at, at).Find(&windows)
+	return len(applicableWindows(db, host, windows)) > 0
+}
+
+// maintenanceFor scopes a query to windows whose host and device type selectors allow the host;
+// group and tag selectors are checked by applicableWindows
+func maintenanceFor(db *gorm.DB, host *models.Host) *gorm.DB {
+	return db.Where("(host_id IS NULL OR host_id = ?) AND (device_type_name = '' OR device_type_name IS NULL OR device_type_name = ?)",
+		host.ID, host.DeviceTypeName)
+}
+
+// applicableWindows keeps the windows whose group and tag selectors match the host
+func applicableWindows(db *gorm.DB, host *models.Host, windows []models.MaintenanceWindow) []models.MaintenanceWindow {
+	var applicable []models.MaintenanceWindow
+	for _, window := range windows {
+		if hostMatches(db, host, window.GroupID, window.TagSelector) {
+			applicable = append(applicable, window)
+		}
+	}
+	return applicable
+}
+
+// maintenanceIntervals returns the merged maintenance periods of the host within [from, to)
+func maintenanceIntervals(db *gorm.DB, host *models.Host, from, to time.Time) []interval {
+	var windows []models.MaintenanceWindow
+	maintenanceFor(db, host).Where("starts_at < ? AND ends_at > ?", to, from).Find(&windows)
+
+	var periods []interval
+	for _, window := range applicableWindows(db, host, windows) {
+		periods = append(periods, clip(interval{window.StartsAt, window.EndsAt}, from, to))
+	}
+	return mergeIntervals(periods)
+}
+
+func clip(i interval, from, to time.Time) interval {
+	if i.Start.Before(from) {
+		i.Start = from
+	}
+	if i.End.After(to) {
+		i.End = to
+	}
+	return i
+}
+
+func mergeIntervals(periods []interval) []interval {
+	sort.Slice(periods, func(i, j int) bool { return periods[i].Start.Before(periods[j].Start) })
+	var merged []interval
+	for _, p := range periods {
+		if !p.End.After(p.Start) {
+			continue
+		}
+		if n := len(merged); n > 0 && !p.Start.After(merged[n-1].End) {
+			if p.End.After(merged[n-1].End) {
+				merged[n-1].End = p.End
+			}
+			continue
+		}
+		merged = append(merged, p)
+	}
+	return merged
+}
+
+// subtractIntervals returns the parts of i not covered by the merged, sorted periods
+func subtractIntervals(i interval, periods []interval) []interval {
+	var rest []interval
+	cursor := i.Start
+	for _, p := range periods {
+		if !p.End.After(cursor) || !p.Start.Before(i.End) {
+			continue
+		}
+		if p.Start.After(cursor) {
+			rest = append(rest, interval{cursor, p.Start})
+		}
+		cursor = p.End
+	}
+	if i.End.After(cursor) {
+		rest = append(rest, interval{cursor, i.End})
+	}
+	return rest
+}
+
+func totalDuration(periods []interval) time.Duration {
+	var total time.Duration
+	for _, p := range periods {
+		total += p.End.Sub(p.Start)
+	}
+	return total
+}
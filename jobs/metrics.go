@@ -0,0 +1,291 @@
+package jobs
+
+import (
+	"alerting-app/database"
+	"alerting-app/events"
+	"alerting-app/models"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// A minimal Prometheus text-format registry; only what the checker exposes.
+
+type labelSet []string // alternating name, value
+
+func (l labelSet) String() string {
+	if len(l) == 0 {
+		return ""
+	}
+	parts := make([]string, 0, len(l)/2)
+	for i := 0; i+1 < len(l); i += 2 {
+		parts = append(parts, fmt.Sprintf(`%s="%s"`, l[i], escapeLabel(l[i+1])))
+	}
+	return "{" + strings.Join(parts, ",") + "}"
+}
+
+func escapeLabel(value string) string {
+	value = strings.ReplaceAll(value, `\`, `\\`)
+	value = strings.ReplaceAll(value, "\n", `\n`)
+	return strings.ReplaceAll(value, `"`, `\"`)
+}
+
+type counterVec struct {
+	mu     sync.Mutex
+	name   string
+	help   string
+	values map[string]float64
+}
+
+func newCounterVec(name, help string) *counterVec {
+	return &counterVec{name: name, help: help, values: map[string]float64{}}
+}
+
+func (c *counterVec) Inc(labels ...string) {
+	c.mu.Lock()
+	c.values[labelSet(labels).String()]++
+	c.mu.Unlock()
+}
+
+func (c *counterVec) write(w io.Writer) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s counter\n", c.name, c.help, c.name)
+	for _, key := range sortedKeys(c.values) {
+		fmt.Fprintf(w, "%s%s %g\n", c.name, key, c.values[key])
+	}
+}
+
+type histogramVec struct {
+	mu      sync.Mutex
+	name    string
+	help    string
+	buckets []float64
+	series  map[string]*histogramSeries
+}
+
+type histogramSeries struct {
+	labels labelSet
+	counts []uint64
+	sum    float64
+	count  uint64
+}
+
+func newHistogramVec(name, help string, buckets []float64) *histogramVec {
+	return &histogramVec{name: name, help: help, buckets: buckets, series: map[string]*histogramSeries{}}
+}
+
+func (h *histogramVec) Observe(value float64, labels ...string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	key := labelSet(labels).String()
+	s, ok := h.series[key]
+	if !ok {
+		s = &histogramSeries{labels: labels, counts: make([]uint64, len(h.buckets))}
+		h.series[key] = s
+	}
+	for i, bound := range h.buckets {
+		if value <= bound {
+			s.counts[i]++
+		}
+	}
+	s.sum += value
+	s.count++
+}
+
+func (h *histogramVec) write(w io.Writer) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s histogram\n", h.name, h.help, h.name)
+	keys := make([]string, 0, len(h.series))
+	for key := range h.series {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		s := h.series[key]
+		for i, bound := range h.buckets {
+			fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, append(append(labelSet{}, s.labels...), "le", fmt.Sprint(bound)), s.counts[i])
+		}
+		fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, append(append(labelSet{}, s.labels...), "le", "+Inf"), s.count)
+		fmt.Fprintf(w, "%s_sum%s %g\n", h.name, key, s.sum)
+		fmt.Fprintf(w, "%s_count%s %d\n", h.name, key, s.count)
+	}
+}
+
+func sortedKeys(values map[string]float64) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+var (
+	checkDuration = newHistogramVec("hostcheck_check_duration_seconds", "Duration of host checks.",
+		[]float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 20})
+	checksTotal               = newCounterVec("hostcheck_checks_total", "Checks performed by method and result.")
+	alertsTotal               = newCounterVec("hostcheck_alerts_total", "Alerts queued by channel and event.")
+	notificationsTotal        = newCounterVec("hostcheck_notifications_total", "Notification delivery attempts by channel and outcome.")
+	notificationFailuresTotal = newCounterVec("hostcheck_notification_failures_total", "Notifications that failed permanently by channel.")
+	schedulerTicksTotal       = newCounterVec("hostcheck_scheduler_ticks_total", "Check scheduler ticks.")
+
+	// Last check per host, kept in memory between scrapes
+	lastChecks = struct {
+		sync.Mutex
+		latency map[uint]float64
+		method  map[uint]string
+	}{latency: map[uint]float64{}, method: map[uint]string{}}
+
+	schedulerStats = struct {
+		sync.Mutex
+		tickStats
+	}{}
+)
+
+type tickStats struct {
+	lastTick      time.Time
+	lastDuration  time.Duration
+	lastChecked   int
+	lastScheduled int
+}
+
+// observeCheck records the metrics of one host check
+func observeCheck(check events.CheckCompleted) {
+	checkDuration.Observe(check.Duration.Seconds(), "method", check.Method)
+	checksTotal.Inc("method", check.Method, "result", check.Status)
+
+	lastChecks.Lock()
+	lastChecks.latency[check.Host.ID] = check.Latency.Seconds()
+	lastChecks.method[check.Host.ID] = check.Method
+	lastChecks.Unlock()
+}
+
+// observeNotification counts one delivery attempt by its outcome
+func observeNotification(notification models.Notification) {
+	switch notification.Status {
+	case models.NotificationSent:
+		notificationsTotal.Inc("channel", notification.ChannelName, "outcome", "sent")
+	case models.NotificationFailed:
+		notificationsTotal.Inc("channel", notification.ChannelName, "outcome", "failed")
+		notificationFailuresTotal.Inc("channel", notification.ChannelName)
+	default:
+		notificationsTotal.Inc("channel", notification.ChannelName, "outcome", "retry")
+	}
+}
+
+// observeTick records scheduler internals for one check tick
+func observeTick(tick events.TickFinished) {
+	schedulerTicksTotal.Inc()
+	schedulerStats.Lock()
+	schedulerStats.tickStats = tickStats{
+		lastTick:      tick.Started,
+		lastDuration:  tick.Duration,
+		lastScheduled: tick.Active,
+		lastChecked:   tick.Checked,
+	}
+	schedulerStats.Unlock()
+}
+
+// WriteMetrics renders every metric in the Prometheus text exposition format
+func WriteMetrics(w io.Writer) error {
+	db := database.DB
+
+	var hosts []models.Host
+	if err := db.Preload("Method").Find(&hosts).Error; err != nil {
+		return err
+	}
+
+	lastChecks.Lock()
+	latency := make(map[uint]float64, len(lastChecks.latency))
+	for id, value := range lastChecks.latency {
+		latency[id] = value
+	}
+	lastChecks.Unlock()
+
+	fmt.Fprint(w, "# HELP hostcheck_host_up Whether the host is up (1), down (0); paused hosts are omitted.\n# TYPE hostcheck_host_up gauge\n")
+	for _, host := range hosts {
+		if !host.IsActive {
+			continue
+		}
+		up := 1
+		if host.AlertStatus {
+			up = 0
+		}
+		fmt.Fprintf(w, "hostcheck_host_up%s %d\n", hostLabels(host), up)
+	}
+
+	fmt.Fprint(w, "# HELP hostcheck_host_consecutive_failures Failed checks since the host was last up.\n# TYPE hostcheck_host_consecutive_failures gauge\n")
+	for _, host := range hosts {
+		if !host.IsActive {
+			continue
+		}
+		fmt.Fprintf(w, "hostcheck_host_consecutive_failures%s %d\n", hostLabels(host), host.RetryCount)
+	}
+
+	fmt.Fprint(w, "# HELP hostcheck_host_latency_seconds Latency of the last check of the host.\n# TYPE hostcheck_host_latency_seconds gauge\n")
+	for _, host := range hosts {
+		if value, ok := latency[host.ID]; ok && host.IsActive {
+			fmt.Fprintf(w, "hostcheck_host_latency_seconds%s %g\n", hostLabels(host), value)
+		}
+	}
+
+	fmt.Fprint(w, "# HELP hostcheck_host_last_check_timestamp_seconds Unix time of the last check of the host.\n# TYPE hostcheck_host_last_check_timestamp_seconds gauge\n")
+	for _, host := range hosts {
+		if host.IsActive && !host.LastCheckedDate.IsZero() {
+			fmt.Fprintf(w, "hostcheck_host_last_check_timestamp_seconds%s %d\n", hostLabels(host), host.LastCheckedDate.Unix())
+		}
+	}
+
+	checkDuration.write(w)
+	checksTotal.write(w)
+	alertsTotal.write(w)
+	notificationsTotal.write(w)
+	notificationFailuresTotal.write(w)
+
+	var pending, openIncidents int64
+	db.Model(&models.Notification{}).Where("status = ?", models.NotificationPending).Count(&pending)
+	db.Model(&models.Incident{}).Where("status = ?", models.IncidentOpen).Count(&openIncidents)
+	fmt.Fprintf(w, "# HELP hostcheck_notifications_pending Notifications waiting in the outbox.\n# TYPE hostcheck_notifications_pending gauge\nhostcheck_notifications_pending %d\n", pending)
+	fmt.Fprintf(w, "# HELP hostcheck_incidents_open Open incidents.\n# TYPE hostcheck_incidents_open gauge\nhostcheck_incidents_open %d\n", openIncidents)
+
+	schedulerTicksTotal.write(w)
+	schedulerStats.Lock()
+	stats := schedulerStats.tickStats
+	schedulerStats.Unlock()
+	lastTick := 0.0
+	if !stats.lastTick.IsZero() {
+		lastTick = float64(stats.lastTick.Unix())
+	}
+	fmt.Fprintf(w, "# HELP hostcheck_scheduler_last_tick_timestamp_seconds Start of the last check tick.\n# TYPE hostcheck_scheduler_last_tick_timestamp_seconds gauge\nhostcheck_scheduler_last_tick_timestamp_seconds %g\n", lastTick)
+	fmt.Fprintf(w, "# HELP hostcheck_scheduler_last_tick_duration_seconds Duration of the last check tick.\n# TYPE hostcheck_scheduler_last_tick_duration_seconds gauge\nhostcheck_scheduler_last_tick_duration_seconds %g\n", stats.lastDuration.Seconds())
+	fmt.Fprintf(w, "# HELP hostcheck_scheduler_active_hosts Active hosts seen by the last tick.\n# TYPE hostcheck_scheduler_active_hosts gauge\nhostcheck_scheduler_active_hosts %d\n", stats.lastScheduled)
+	fmt.Fprintf(w, "# HELP hostcheck_scheduler_hosts_checked Hosts checked by the last tick.\n# TYPE hostcheck_scheduler_hosts_checked gauge\nhostcheck_scheduler_hosts_checked %d\n", stats.lastChecked)
+	fmt.Fprintf(w, "# HELP hostcheck_scheduler_jobs Jobs registered with the scheduler.\n# TYPE hostcheck_scheduler_jobs gauge\nhostcheck_scheduler_jobs %d\n", cronChecker.Len()+localScheduler.Len())
+
+	fmt.Fprint(w, "# HELP hostcheck_event_handler_pending Events queued for each internal event handler.\n# TYPE hostcheck_event_handler_pending gauge\n")
+	for _, stats := range events.Default.Stats() {
+		fmt.Fprintf(w, "hostcheck_event_handler_pending%s %d\n", labelSet{"handler", stats.Name}, stats.Pending)
+	}
+	fmt.Fprint(w, "# HELP hostcheck_event_handler_handled_total Events processed by each internal event handler.\n# TYPE hostcheck_event_handler_handled_total counter\n")
+	for _, stats := range events.Default.Stats() {
+		fmt.Fprintf(w, "hostcheck_event_handler_handled_total%s %d\n", labelSet{"handler", stats.Name}, stats.Handled)
+	}
+
+	uptime := math.Max(0, time.Since(processStart).Seconds())
+	fmt.Fprintf(w, "# HELP hostcheck_uptime_seconds Seconds since the checker started.\n# TYPE hostcheck_uptime_seconds gauge\nhostcheck_uptime_seconds %g\n", uptime)
+	return nil
+}
+
+var processStart = time.Now()
+
+// hostLabels identifies a host's series; names are not unique, so the id keeps two same-named hosts apart
+func hostLabels(host models.Host) labelSet {
+	return labelSet{"id", strconv.FormatUint(uint64(host.ID), 10), "host", host.Name, "device_type", host.DeviceTypeName, "method", host.Method.Method}
+}
@@ -0,0 +1,106 @@
+package jobs
+
+import (
+	"alerting-app/config"
+	"alerting-app/database"
+	"alerting-app/models"
+	"fmt"
+	"strings"
+)
+
+// notifier delivers one notification through a channel and returns the provider's response
+type notifier func(channel models.AlertChannel, notification *models.Notification) (string, error)
+
+// notifiers maps an AlertChannel provider to its delivery function
+var notifiers = map[string]notifier{
+	"telegram": telegramNotifier,
+	"mail":     mailNotifier,
+	"hook":     sendWebhookAlert,
+	"slack":    slackNotifier,
+	"teams":    teamsNotifier,
+	"discord":  discordNotifier,
+}
+
+// channelProvider falls back to the channel name for channels created before providers existed
+func channelProvider(channel models.AlertChannel) string {
+	if channel.Provider != "" {
+		return channel.Provider
+	}
+	return channel.Name
+}
+
+// IsKnownProvider reports whether a notifier exists for the provider
+func IsKnownProvider(provider string) bool {
+	_, ok := notifiers[provider]
+	return ok
+}
+
+func telegramNotifier(channel models.AlertChannel, notification *models.Notification) (string, error) {
+	chatID := channel.Config3
+	if notification.Recipient != "" {
+		chatID = notification.Recipient
+	}
+	params := map[string]interface{}{
+		"chat_id": chatID,
+		"text":    notification.Message,
+	}
+	if markup := telegramAlertButtons(notification); markup != nil {
+		params["reply_markup"] = markup
+	}
+	return telegramCall(channel.Config1, channel.Config2, "sendMessage", params)
+}
+
+func mailNotifier(channel models.AlertChannel, notification *models.Notification) (string, error) {
+	return sendMailAlert(channel, notification.Recipient, "Host alert: "+notification.HostName, notification.Message)
+}
+
+// alertDetails is the provider independent view rich notifiers render from
+type alertDetails struct {
+	Title        string
+	Message      string
+	Status       string
+	Color        string // hex without '#'
+	Fields       [][2]string
+	DashboardURL string
+}
+
+var eventColors = map[string]string{
+	"down":       "D32F2F",
+	"up":         "2E7D32",
+	"escalation": "EF6C00",
+	"reminder":   "EF6C00",
+}
+
+// buildAlertDetails loads the host behind a notification and collects what rich notifiers show
+func buildAlertDetails(notification *models.Notification) alertDetails {
+	details := alertDetails{
+		Title:        fmt.Sprintf("%s: %s", strings.ToUpper(notification.Event), notification.HostName),
+		Message:      notification.Message,
+		Status:       notification.Event,
+		Color:        "1565C0",
+		DashboardURL: strings.TrimRight(config.Config("DASHBOARD_URL"), "/"),
+	}
+	if color, ok := eventColors[notification.Event]; ok {
+		details.Color = color
+	}
+
+	if notification.HostID != 0 {
+		var host models.Host
+		if err := database.DB.Unscoped().Preload("Method").First(&host, notification.HostID).Error; err == nil {
+			details.Fields = append(details.Fields,
+				[2]string{"Host", host.Name},
+				[2]string{"Address", host.IP},
+			)
+			if host.DeviceTypeName != "" {
+				details.Fields = append(details.Fields, [2]string{"Device type", host.DeviceTypeName})
+			}
+			if host.Method.Method != "" {
+				details.Fields = append(details.Fields, [2]string{"Check", host.Method.Method})
+			}
+		}
+	}
+	if notification.IncidentID != nil {
+		details.Fields = append(details.Fields, [2]string{"Incident", fmt.Sprintf("#%d", *notification.IncidentID)})
+	}
+	return details
+}
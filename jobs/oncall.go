@@ -0,0 +1,147 @@
+package jobs
+
+import (
+	"alerting-app/models"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// contactProviders maps a user contact type to the provider that delivers it
+var contactProviders = map[string]string{
+	"telegram": "telegram",
+	"email":    "mail",
+	"webhook":  "hook",
+}
+
+// WhoIsOnCall returns the user on call for the schedule at the given time.
+// Overrides win over the rotation; nil means nobody is on call.
+func WhoIsOnCall(db *gorm.DB, schedule *models.OnCallSchedule, at time.Time) (*models.User, error) {
+	var override models.OnCallOverride
+	err := db.Preload("User").
+		Where("schedule_id = ? AND starts_at <= ? AND ends_at > ?", schedule.ID, at, at).
+		Order("starts_at DESC").First(&override).Error
+	if err == nil {
+		return &override.User, nil
+	}
+	if err != gorm.ErrRecordNotFound {
+		return nil, err
+	}
+
+	var members []models.OnCallMember
+	if err := db.Preload("User").Where("schedule_id = ?", schedule.ID).Find(&members).Error; err != nil {
+		return nil, err
+	}
+	if len(members) == 0 {
+		return nil, nil
+	}
+	sort.Slice(members, func(i, j int) bool { return members[i].Position < members[j].Position })
+
+	slot, err := rotationSlot(schedule, at)
+	if err != nil {
+		return nil, err
+	}
+	index := slot % len(members)
+	if index < 0 {
+		index += len(members)
+	}
+	return &members[index].User, nil
+}
+
+// rotationSlot counts how many rotations have passed since RotationStart.
+// Days are counted on the schedule's local calendar so DST changes do not shift handoffs.
+func rotationSlot(schedule *models.OnCallSchedule, at time.Time) (int, error) {
+	loc, err := time.LoadLocation(schedule.Timezone)
+	if err != nil {
+		return 0, fmt.Errorf("invalid timezone %q: %v", schedule.Timezone, err)
+	}
+	handoff, err := time.Parse("15:04", schedule.HandoffTime)
+	if err != nil {
+		return 0, fmt.Errorf("invalid handoff_time %q: %v", schedule.HandoffTime, err)
+	}
+	days := schedule.RotationDays
+	if days <= 0 {
+		days = 7
+	}
+
+	local := at.In(loc)
+	day := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, time.UTC)
+	if local.Hour()*60+local.Minute() < handoff.Hour()*60+handoff.Minute() {
+		day = day.AddDate(0, 0, -1)
+	}
+
+	start := schedule.RotationStart.In(loc)
+	startDay := time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, time.UTC)
+
+	elapsed := int(day.Sub(startDay).Hours() / 24)
+	slot := elapsed / days
+	if elapsed < 0 && elapsed%days != 0 {
+		slot--
+	}
+	return slot, nil
+}
+
+// notifyUser queues the message for every contact method of the user
+func notifyUser(db *gorm.DB, host *models.Host, incident *models.Incident, userID uint, event, message string) {
+	var contacts []models.UserContact
+	if err := db.Where("user_id = ?", userID).Order("priority ASC").Find(&contacts).Error; err != nil {
+		log.Printf("Failed to load contacts for user %d: %v", userID, err)
+		return
+	}
+	if len(contacts) == 0 {
+		log.Printf("User %d has no contact methods, escalation for host %s not delivered", userID, host.Name)
+		return
+	}
+
+	for _, contact := range contacts {
+		provider, ok := contactProviders[contact.Type]
+		if !ok {
+			continue
+		}
+		channelName := channelForProvider(db, provider)
+		if channelName == "" {
+			log.Printf("No %s channel configured, cannot reach user %d via %s", provider, userID, contact.Type)
+			continue
+		}
+		notification := newNotification(host, channelName, event, message)
+		notification.Recipient = contact.Value
+		notification.UserID = &userID
+		if incident != nil {
+			notification.IncidentID = &incident.ID
+		}
+		queueNotification(db, notification)
+	}
+}
+
+// notifyOnCall queues the message for whoever is on call for the schedule right now
+func notifyOnCall(db *gorm.DB, host *models.Host, incident *models.Incident, scheduleID uint, event, message string) {
+	var schedule models.OnCallSchedule
+	if err := db.First(&schedule, scheduleID).Error; err != nil {
+		log.Printf("Failed to load on-call schedule %d: %v", scheduleID, err)
+		return
+	}
+
+	user, err := WhoIsOnCall(db, &schedule, time.Now())
+	if err != nil {
+		log.Printf("Failed to resolve on-call user for schedule %s: %v", schedule.Name, err)
+		return
+	}
+	if user == nil {
+		log.Printf("Nobody is on call for schedule %s, escalation for host %s not delivered", schedule.Name, host.Name)
+		return
+	}
+	notifyUser(db, host, incident, user.ID, event, message)
+}
+
+// channelForProvider picks the first channel delivering through the provider
+func channelForProvider(db *gorm.DB, provider string) string {
+	var channel models.AlertChannel
+	if err := db.Where("provider = ? OR ((provider = '' OR provider IS NULL) AND name = ?)", provider, provider).
+		Order("id ASC").First(&channel).Error; err != nil {
+		return ""
+	}
+	return channel.Name
+}
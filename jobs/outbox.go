@@ -0,0 +1,190 @@
+package jobs
+
+import (
+	"alerting-app/database"
+	"alerting-app/events"
+	"alerting-app/models"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	outboxBatchSize    = 50
+	outboxBaseBackoff  = 30 * time.Second
+	outboxMaxBackoff   = time.Hour
+	defaultMaxAttempts = 8
+)
+
+// errUnsupportedChannel marks deliveries that will never succeed, so they are not retried
+var errUnsupportedChannel = errors.New("unsupported alert channel")
+
+// enqueueNotification stores an alert in the outbox; the dispatcher delivers it
+func enqueueNotification(db *gorm.DB, host *models.Host, incident *models.Incident, channelName, event, message string) {
+	if hostMuted(host) {
+		log.Printf("Host %s is muted, %s alert not queued", host.Name, event)
+		return
+	}
+	if inMaintenance(db, host, time.Now()) {
+		log.Printf("Host %s is in maintenance, %s alert not queued", host.Name, event)
+		return
+	}
+
+	notification := newNotification(host, channelName, event, message)
+	if incident != nil {
+		notification.IncidentID = &incident.ID
+	}
+	applyAlertGrouping(db, host, notification)
+	queueNotification(db, notification)
+	holdForTick(notification)
+}
+
+// enqueueIncidentNotification queues an alert that belongs to an incident
+func enqueueIncidentNotification(db *gorm.DB, host *models.Host, incident *models.Incident, channelName, event, message string) {
+	notification := newNotification(host, channelName, event, message)
+	notification.IncidentID = &incident.ID
+	queueNotification(db, notification)
+}
+
+// hostMuted reports whether notifications for the host are silenced right now
+func hostMuted(host *models.Host) bool {
+	return host.MutedUntil != nil && host.MutedUntil.After(time.Now())
+}
+
+func newNotification(host *models.Host, channelName, event, message string) *models.Notification {
+	return &models.Notification{
+		HostID:        host.ID,
+		HostName:      host.Name,
+		ChannelName:   channelName,
+		Event:         event,
+		Message:       message,
+		Severity:      host.Severity,
+		Status:        models.NotificationPending,
+		MaxAttempts:   defaultMaxAttempts,
+		NextAttemptAt: time.Now(),
+	}
+}
+
+func queueNotification(db *gorm.DB, notification *models.Notification) {
+	if notification.ChannelName == "" {
+		return
+	}
+	applyQuietHours(db, notification)
+	if err := db.Create(notification).Error; err != nil {
+		log.Printf("Failed to queue %s notification for host %s: %v", notification.Event, notification.HostName, err)
+		return
+	}
+	alertsTotal.Inc("channel", notification.ChannelName, "event", notification.Event)
+}
+
+// dispatchNotifications delivers every pending notification that is due
+func dispatchNotifications() {
+	db := database.DB
+	mergeNotificationGroups(db)
+
+	var pending []models.Notification
+	if err := db.Where("status = ? AND next_attempt_at <= ?", models.NotificationPending, time.Now()).
+		Order("next_attempt_at ASC").Limit(outboxBatchSize).Find(&pending).Error; err != nil {
+		log.Println("Failed to load pending notifications:", err)
+		return
+	}
+
+	for i := range pending {
+		deliverNotification(db, &pending[i])
+	}
+}
+
+// deliverNotification makes one delivery attempt and records its outcome
+func deliverNotification(db *gorm.DB, notification *models.Notification) error {
+	response, err := sendNotification(db, notification)
+	notification.Attempts++
+	notification.Response = response
+
+	if err == nil {
+		now := time.Now()
+		notification.Status = models.NotificationSent
+		notification.SentAt = &now
+		notification.LastError = ""
+		log.Printf("Notification %d delivered to %s for host %s", notification.ID, notification.ChannelName, notification.HostName)
+	} else {
+		notification.LastError = err.Error()
+		if errors.Is(err, errUnsupportedChannel) || notification.Attempts >= notification.MaxAttempts {
+			notification.Status = models.NotificationFailed
+			log.Printf("Notification %d failed permanently after %d attempts: %v", notification.ID, notification.Attempts, err)
+		} else {
+			notification.NextAttemptAt = time.Now().Add(outboxBackoff(notification.Attempts))
+			log.Printf("Notification %d attempt %d failed, retrying at %s: %v", notification.ID, notification.Attempts, notification.NextAttemptAt.Format("2006-01-02 15:04:05"), err)
+		}
+	}
+
+	if saveErr := db.Save(notification).Error; saveErr != nil {
+		log.Printf("Failed to update notification %d: %v", notification.ID, saveErr)
+	}
+	event := events.NotificationSent{Notification: *notification, Error: err}
+	if notification.HostID != 0 {
+		event.DeviceType = hostDeviceType(notification.HostID)
+	}
+	events.Publish(event)
+	return err
+}
+
+// sendNotification routes the notification to its channel's provider
+func sendNotification(db *gorm.DB, notification *models.Notification) (string, error) {
+	var channel models.AlertChannel
+	if err := db.Where("name = ?", notification.ChannelName).First(&channel).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return "", fmt.Errorf("%w: channel %q not found", errUnsupportedChannel, notification.ChannelName)
+		}
+		return "", fmt.Errorf("failed to load channel: %v", err)
+	}
+
+	provider := channelProvider(channel)
+	send, ok := notifiers[provider]
+	if !ok {
+		return "", fmt.Errorf("%w: %s", errUnsupportedChannel, provider)
+	}
+	return send(channel, notification)
+}
+
+// outboxBackoff doubles the retry delay with every failed attempt
+func outboxBackoff(attempts int) time.Duration {
+	delay := time.Duration(float64(outboxBaseBackoff) * math.Pow(2, float64(attempts-1)))
+	if delay > outboxMaxBackoff || delay <= 0 {
+		return outboxMaxBackoff
+	}
+	return delay
+}
+
+// ResendNotification resets a notification and attempts delivery immediately
+func ResendNotification(notification *models.Notification) error {
+	notification.Status = models.NotificationPending
+	notification.Attempts = 0
+	notification.NextAttemptAt = time.Now()
+	notification.LastError = ""
+	return deliverNotification(database.DB, notification)
+}
+
+// SendTestNotification delivers a sample message through the channel right away.
+// The attempt is recorded in the notification history but never retried; the row is
+// inserted as sending so the dispatcher cannot deliver it a second time.
+func SendTestNotification(channel *models.AlertChannel, recipient string) (*models.Notification, error) {
+	db := database.DB
+	notification := &models.Notification{
+		HostName:      "test",
+		ChannelName:   channel.Name,
+		Recipient:     recipient,
+		Event:         "test",
+		Message:       fmt.Sprintf("Test message from host-checker for channel %s (%s) sent at %s", channel.Name, channelProvider(*channel), time.Now().Format("2006-01-02 15:04:05")),
+		Status:        models.NotificationSending,
+		MaxAttempts:   1,
+		NextAttemptAt: time.Now(),
+	}
+	if err := db.Create(notification).Error; err != nil {
+		return nil, err
+	}
+	return notification, deliverNotification(db, notification)
+}
@@ -0,0 +1,158 @@
+package jobs
+
+import (
+	"alerting-app/models"
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+// sendMailAlert delivers a message over SMTP.
+// Channel config: Config1 "host:port", Config2 username (also the sender), Config3 password,
+// Config4 comma separated default recipients.
+func sendMailAlert(channel models.AlertChannel, recipient, subject, message string) (string, error) {
+	to := splitList(channel.Config4)
+	if recipient != "" {
+		to = splitList(recipient)
+	}
+	if len(to) == 0 {
+		return "", fmt.Errorf("%w: mail channel %q has no recipients", errUnsupportedChannel, channel.Name)
+	}
+
+	host, _, err := net.SplitHostPort(channel.Config1)
+	if err != nil {
+		return "", fmt.Errorf("%w: invalid SMTP address %q", errUnsupportedChannel, channel.Config1)
+	}
+
+	var auth smtp.Auth
+	if channel.Config2 != "" {
+		auth = smtp.PlainAuth("", channel.Config2, channel.Config3, host)
+	}
+
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", channel.Config2)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(to, ", "))
+	fmt.Fprintf(&msg, "Subject: %s\r\n", subject)
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/plain; charset=\"utf-8\"\r\n\r\n")
+	msg.WriteString(message)
+
+	if err := smtp.SendMail(channel.Config1, auth, channel.Config2, to, msg.Bytes()); err != nil {
+		return "", fmt.Errorf("failed to send mail: %v", err)
+	}
+	return "accepted for " + strings.Join(to, ", "), nil
+}
+
+// sendMailWithAttachment sends a multipart mail through the channel's SMTP server
+func sendMailWithAttachment(channel models.AlertChannel, to []string, subject, body, filename, contentType string, data []byte) error {
+	host, _, err := net.SplitHostPort(channel.Config1)
+	if err != nil {
+		return fmt.Errorf("invalid SMTP address %q", channel.Config1)
+	}
+	var auth smtp.Auth
+	if channel.Config2 != "" {
+		auth = smtp.PlainAuth("", channel.Config2, channel.Config3, host)
+	}
+
+	boundary := fmt.Sprintf("hostchecker-%d", time.Now().UnixNano())
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", channel.Config2)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(to, ", "))
+	fmt.Fprintf(&msg, "Subject: %s\r\n", subject)
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&msg, "Content-Type: multipart/mixed; boundary=%q\r\n\r\n", boundary)
+
+	fmt.Fprintf(&msg, "--%s\r\n", boundary)
+	msg.WriteString("Content-Type: text/plain; charset=\"utf-8\"\r\n\r\n")
+	msg.WriteString(body + "\r\n")
+
+	fmt.Fprintf(&msg, "--%s\r\n", boundary)
+	fmt.Fprintf(&msg, "Content-Type: %s\r\n", contentType)
+	msg.WriteString("Content-Transfer-Encoding: base64\r\n")
+	fmt.Fprintf(&msg, "Content-Disposition: attachment; filename=%q\r\n\r\n", filename)
+	encoded := base64.StdEncoding.EncodeToString(data)
+	for len(encoded) > 76 {
+		msg.WriteString(encoded[:76] + "\r\n")
+		encoded = encoded[76:]
+	}
+	msg.WriteString(encoded + "\r\n")
+	fmt.Fprintf(&msg, "--%s--\r\n", boundary)
+
+	if err := smtp.SendMail(channel.Config1, auth, channel.Config2, to, msg.Bytes()); err != nil {
+		return fmt.Errorf("failed to send mail: %v", err)
+	}
+	return nil
+}
+
+// sendWebhookAlert posts the notification as JSON.
+// Channel config: Config1 URL, Config2 optional Authorization header value, sent to Config1 only
+// so a user's own webhook contact never receives the channel's credential.
+func sendWebhookAlert(channel models.AlertChannel, notification *models.Notification) (string, error) {
+	url := channel.Config1
+	if notification.Recipient != "" {
+		url = notification.Recipient
+	}
+	if url == "" {
+		return "", fmt.Errorf("%w: webhook channel %q has no URL", errUnsupportedChannel, channel.Name)
+	}
+
+	body, err := json.Marshal(map[string]interface{}{
+		"id":          notification.ID,
+		"host_id":     notification.HostID,
+		"host_name":   notification.HostName,
+		"incident_id": notification.IncidentID,
+		"event":       notification.Event,
+		"message":     notification.Message,
+		"created_at":  notification.CreatedAt,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal webhook body: %v", err)
+	}
+
+	req, err := http.NewRequest("POST", url, bytes.NewBuffer(body))
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if channel.Config2 != "" && url == channel.Config1 {
+		req.Header.Set("Authorization", channel.Config2)
+	}
+
+	return doProviderRequest(req)
+}
+
+// doProviderRequest performs a provider HTTP call and treats any 2xx as success
+func doProviderRequest(req *http.Request) (string, error) {
+	client := &http.Client{
+		Timeout: 10 * time.Second,
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to send request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return string(respBody), fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+	return string(respBody), nil
+}
+
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
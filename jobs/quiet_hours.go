@@ -0,0 +1,87 @@
+package jobs
+
+import (
+	"alerting-app/models"
+	"fmt"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// quietGroupKey collects deferred notifications so they are delivered as one batch
+const quietGroupKey = "quiet-hours"
+
+// applyQuietHours defers the notification to the end of any matching quiet period
+// when its severity is below the rule's minimum
+func applyQuietHours(db *gorm.DB, notification *models.Notification) {
+	if notification.Severity == "" {
+		return
+	}
+
+	query := db.Where("channel_name = ? AND user_id IS NULL", notification.ChannelName)
+	if notification.UserID != nil {
+		query = db.Where("(channel_name = ? AND user_id IS NULL) OR (user_id = ? AND (channel_name = '' OR channel_name = ?))",
+			notification.ChannelName, *notification.UserID, notification.ChannelName)
+	}
+	var rules []models.QuietHours
+	if err := query.Find(&rules).Error; err != nil {
+		log.Println("Failed to load quiet hours:", err)
+		return
+	}
+
+	now := time.Now()
+	for _, rule := range rules {
+		if models.SeverityLevels[notification.Severity] >= models.SeverityLevels[rule.MinSeverity] {
+			continue
+		}
+		end, quiet, err := QuietPeriodEnd(rule, now)
+		if err != nil {
+			log.Printf("Invalid quiet hours rule %d: %v", rule.ID, err)
+			continue
+		}
+		if quiet && end.After(notification.NextAttemptAt) {
+			notification.NextAttemptAt = end
+			notification.Deferred = true
+			notification.GroupKey = quietGroupKey
+		}
+	}
+}
+
+// QuietPeriodEnd reports whether the rule is active at the given time and when the quiet period ends
+func QuietPeriodEnd(rule models.QuietHours, at time.Time) (time.Time, bool, error) {
+	loc, err := time.LoadLocation(rule.Timezone)
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("invalid timezone %q", rule.Timezone)
+	}
+	start, err := time.Parse("15:04", rule.Start)
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("invalid start %q", rule.Start)
+	}
+	end, err := time.Parse("15:04", rule.End)
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("invalid end %q", rule.End)
+	}
+
+	local := at.In(loc)
+	minute := local.Hour()*60 + local.Minute()
+	startMinute := start.Hour()*60 + start.Minute()
+	endMinute := end.Hour()*60 + end.Minute()
+	endToday := time.Date(local.Year(), local.Month(), local.Day(), end.Hour(), end.Minute(), 0, 0, loc)
+
+	switch {
+	case startMinute == endMinute:
+		return time.Time{}, false, nil
+	case startMinute < endMinute:
+		// Same-day window, e.g. 12:00-14:00
+		return endToday, minute >= startMinute && minute < endMinute, nil
+	case minute < endMinute:
+		// Overnight window, after midnight
+		return endToday, true, nil
+	case minute >= startMinute:
+		// Overnight window, before midnight
+		return endToday.AddDate(0, 0, 1), true, nil
+	default:
+		return time.Time{}, false, nil
+	}
+}
@@ -0,0 +1,138 @@
+package jobs
+
+import (
+	"alerting-app/models"
+	"bytes"
+	"fmt"
+	"html/template"
+	"math"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// reportPage is the data rendered by reportTemplate
+type reportPage struct {
+	Title     string
+	Generated time.Time
+	Report    *UptimeReport
+	Incidents []models.Incident
+	ChartRows []chartRow
+	ChartH    int
+}
+
+type chartRow struct {
+	Name   string
+	Y      int
+	Width  float64
+	Label  string
+	Color  string
+	Target float64 // x position of the SLA marker, 0 for none
+}
+
+var reportTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"pct": func(v float64) string { return fmt.Sprintf("%.3f%%", v) },
+	"mins": func(v *float64) string {
+		if v == nil {
+			return "-"
+		}
+		return formatMinutes(*v)
+	},
+	"dur":  func(v float64) string { return formatMinutes(v) },
+	"date": func(t time.Time) string { return t.Format("2006-01-02 15:04") },
+	"optdate": func(t *time.Time) string {
+		if t == nil {
+			return "ongoing"
+		}
+		return t.Format("2006-01-02 15:04")
+	},
+}).Parse(`<!DOCTYPE html>
+<html><head><meta charset="utf-8"><title>{{.Title}}</title>
+<style>
+body{font-family:Arial,Helvetica,sans-serif;color:#222;margin:24px}
+h1{font-size:22px;margin-bottom:4px}h2{font-size:17px;margin-top:28px;border-bottom:1px solid #ddd;padding-bottom:4px}
+.muted{color:#777;font-size:13px}
+.cards{display:flex;gap:12px;margin-top:16px}
+.card{border:1px solid #ddd;border-radius:6px;padding:10px 16px;min-width:120px}
+.card b{display:block;font-size:20px}
+table{border-collapse:collapse;width:100%;font-size:13px;margin-top:8px}
+th,td{border:1px solid #e0e0e0;padding:5px 8px;text-align:left}th{background:#f5f5f5}
+.bad{color:#c62828;font-weight:bold}.ok{color:#2e7d32}
+</style></head><body>
+<h1>{{.Title}}</h1>
+<div class="muted">{{date .Report.From}} &ndash; {{date .Report.To}} &middot; generated {{date .Generated}}</div>
+<div class="cards">
+<div class="card">Uptime<b>{{pct .Report.Total.UptimePercent}}</b></div>
+<div class="card">Hosts<b>{{.Report.Total.Hosts}}</b></div>
+<div class="card">Incidents<b>{{.Report.Total.Incidents}}</b></div>
+<div class="card">MTTR<b>{{mins .Report.Total.MTTRMinutes}}</b></div>
+<div class="card">SLA breaches<b>{{.Report.Total.BreachedHosts}}</b></div>
+</div>
+<h2>Availability</h2>
+<svg width="760" height="{{.ChartH}}" xmlns="http://www.w3.org/2000/svg" font-size="11" font-family="Arial">
+{{range .ChartRows}}<text x="0" y="{{.Y}}" dy="12">{{.Name}}</text>
+<rect x="200" y="{{.Y}}" width="480" height="14" fill="#eee"/>
+<rect x="200" y="{{.Y}}" width="{{.Width}}" height="14" fill="{{.Color}}"/>
+{{if .Target}}<rect x="{{.Target}}" y="{{.Y}}" width="2" height="14" fill="#000"><title>SLA target</title></rect>{{end}}
+<text x="688" y="{{.Y}}" dy="12">{{.Label}}</text>
+{{end}}</svg>
+<table><tr><th>{{if eq .Report.GroupBy "host"}}Host{{else}}Group{{end}}</th><th>Hosts</th><th>Uptime</th><th>Incidents</th><th>Downtime</th><th>MTTR</th><th>MTBF</th><th>SLA</th></tr>
+{{range .Report.Rows}}<tr><td>{{.Name}}</td><td>{{.Hosts}}</td><td>{{pct .UptimePercent}}</td><td>{{.Incidents}}</td><td>{{dur .DowntimeMinutes}}</td><td>{{mins .MTTRMinutes}}</td><td>{{mins .MTBFMinutes}}</td>
+<td>{{if .SLABreached}}<span class="bad">breached{{if .SLATarget}} ({{pct .SLATarget}}){{end}}</span>{{else if .SLATarget}}<span class="ok">met ({{pct .SLATarget}})</span>{{else}}-{{end}}</td></tr>
+{{end}}</table>
+<h2>Incidents</h2>
+{{if .Incidents}}<table><tr><th>#</th><th>Host</th><th>Opened</th><th>Resolved</th><th>Acknowledged by</th></tr>
+{{range .Incidents}}<tr><td>{{.ID}}</td><td>{{.HostName}}</td><td>{{date .OpenedAt}}</td><td>{{optdate .ResolvedAt}}</td><td>{{.AcknowledgedBy}}</td></tr>
+{{end}}</table>{{else}}<p class="muted">No incidents in this period.</p>{{end}}
+</body></html>
+`))
+
+// RenderReportHTML renders a self-contained HTML document for the report
+func RenderReportHTML(db *gorm.DB, title string, report *UptimeReport, scope ReportScope) ([]byte, error) {
+	page := reportPage{Title: title, Generated: time.Now(), Report: report}
+
+	hosts, err := scopeHosts(db, scope)
+	if err != nil {
+		return nil, err
+	}
+	query := db.Where("opened_at < ? AND (resolved_at IS NULL OR resolved_at >= ?)", report.To, report.From).
+		Where("host_id IN (?)", hosts.Select("id"))
+	if err := query.Order("opened_at ASC").Find(&page.Incidents).Error; err != nil {
+		return nil, err
+	}
+
+	// Scale the bars to the lowest uptime so small differences stay visible
+	floor := 100.0
+	for _, row := range report.Rows {
+		floor = math.Min(floor, row.UptimePercent)
+	}
+	floor = math.Max(0, math.Floor(floor)-1)
+	scale := func(v float64) float64 { return 480 * (v - floor) / (100 - floor) }
+
+	for i, row := range report.Rows {
+		bar := chartRow{Name: row.Name, Y: 8 + i*20, Width: math.Max(0, scale(row.UptimePercent)), Label: fmt.Sprintf("%.2f%%", row.UptimePercent), Color: "#2e7d32"}
+		if row.SLABreached {
+			bar.Color = "#c62828"
+		}
+		if row.SLATarget > floor {
+			bar.Target = 200 + scale(row.SLATarget)
+		}
+		page.ChartRows = append(page.ChartRows, bar)
+	}
+	page.ChartH = 16 + len(page.ChartRows)*20
+
+	var out bytes.Buffer
+	if err := reportTemplate.Execute(&out, page); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+func formatMinutes(minutes float64) string {
+	d := time.Duration(minutes * float64(time.Minute)).Round(time.Minute)
+	if d >= 24*time.Hour {
+		days := d / (24 * time.Hour)
+		return fmt.Sprintf("%dd %s", days, (d - days*24*time.Hour).String())
+	}
+	return d.String()
+}
@@ -0,0 +1,196 @@
+package jobs
+
+import (
+	"alerting-app/database"
+	"alerting-app/models"
+	"bytes"
+	"errors"
+	"fmt"
+	"log"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// reportRunDelay leaves time for late checks and rollups before a period is reported
+const reportRunDelay = 6 * time.Hour
+
+// ReportPeriod returns the last complete period of the definition before now
+func ReportPeriod(def *models.ReportDefinition, now time.Time) (time.Time, time.Time) {
+	now = now.In(time.Local)
+	midnight := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+	if def.Period == "monthly" {
+		end := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.Local)
+		return end.AddDate(0, -1, 0), end
+	}
+	// Weeks run Monday to Monday
+	offset := (int(midnight.Weekday()) + 6) % 7
+	end := midnight.AddDate(0, 0, -offset)
+	return end.AddDate(0, 0, -7), end
+}
+
+// NextReportRun is when the period following now has completed
+func NextReportRun(def *models.ReportDefinition, now time.Time) time.Time {
+	_, end := ReportPeriod(def, now)
+	next := end.AddDate(0, 0, 7)
+	if def.Period == "monthly" {
+		next = end.AddDate(0, 1, 0)
+	}
+	return next.Add(reportRunDelay)
+}
+
+// ReportDefinitionScope parses the stored scope of a definition
+func ReportDefinitionScope(def *models.ReportDefinition) (ReportScope, error) {
+	scope := ReportScope{DeviceType: def.DeviceType, GroupID: def.GroupID, TagSelector: def.TagSelector}
+	for _, id := range splitList(def.HostIDs) {
+		parsed, err := strconv.ParseUint(id, 10, 64)
+		if err != nil {
+			return scope, fmt.Errorf("invalid host id %q", id)
+		}
+		scope.HostIDs = append(scope.HostIDs, uint(parsed))
+	}
+	return scope, nil
+}
+
+// GeneratedReport is a rendered report document
+type GeneratedReport struct {
+	Report      *UptimeReport
+	Content     []byte
+	ContentType string
+	Filename    string
+}
+
+// GenerateReport renders the definition for its last complete period
+func GenerateReport(db *gorm.DB, def *models.ReportDefinition, now time.Time) (*GeneratedReport, error) {
+	scope, err := ReportDefinitionScope(def)
+	if err != nil {
+		return nil, err
+	}
+	from, to := ReportPeriod(def, now)
+	report, err := BuildUptimeReport(db, from, to, def.GroupBy, scope)
+	if err != nil {
+		return nil, err
+	}
+
+	title := fmt.Sprintf("%s: availability %s - %s", def.Name, from.Format("2006-01-02"), to.AddDate(0, 0, -1).Format("2006-01-02"))
+	html, err := RenderReportHTML(db, title, report, scope)
+	if err != nil {
+		return nil, err
+	}
+
+	base := fmt.Sprintf("%s-%s", strings.ReplaceAll(strings.ToLower(def.Name), " ", "-"), from.Format("20060102"))
+	generated := &GeneratedReport{Report: report, Content: html, ContentType: "text/html; charset=utf-8", Filename: base + ".html"}
+	if def.Format == "pdf" {
+		pdf, err := htmlToPDF(html)
+		if err != nil {
+			return generated, err
+		}
+		generated.Content, generated.ContentType, generated.Filename = pdf, "application/pdf", base+".pdf"
+	}
+	return generated, nil
+}
+
+var errPDFUnavailable = errors.New("PDF output needs wkhtmltopdf in PATH")
+
+// htmlToPDF converts through wkhtmltopdf, which is optional
+func htmlToPDF(html []byte) ([]byte, error) {
+	path, err := exec.LookPath("wkhtmltopdf")
+	if err != nil {
+		return nil, errPDFUnavailable
+	}
+	cmd := exec.Command(path, "--quiet", "-", "-")
+	cmd.Stdin = bytes.NewReader(html)
+	var out, stderr bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("wkhtmltopdf failed: %v: %s", err, stderr.String())
+	}
+	return out.Bytes(), nil
+}
+
+// RunReportDefinition generates the report and delivers it to the recipients and channel
+func RunReportDefinition(def *models.ReportDefinition) error {
+	db := database.DB
+	now := time.Now()
+
+	var problems []string
+	generated, err := GenerateReport(db, def, now)
+	if errors.Is(err, errPDFUnavailable) && generated != nil {
+		// Still deliver the HTML version
+		problems = append(problems, err.Error()+", sent HTML instead")
+	} else if err != nil {
+		problems = append(problems, err.Error())
+	}
+
+	if generated != nil {
+		if recipients := splitList(def.Recipients); len(recipients) > 0 {
+			if err := mailReport(db, def, generated, recipients); err != nil {
+				problems = append(problems, err.Error())
+			}
+		}
+		if def.ChannelName != "" {
+			total := generated.Report.Total
+			message := fmt.Sprintf("Report %s (%s - %s): uptime %.3f%%, %d incidents, %d SLA breaches across %d hosts",
+				def.Name, generated.Report.From.Format("2006-01-02"), generated.Report.To.Format("2006-01-02"),
+				total.UptimePercent, total.Incidents, total.BreachedHosts, total.Hosts)
+			queueNotification(db, &models.Notification{
+				HostName:      "report",
+				ChannelName:   def.ChannelName,
+				Event:         "report",
+				Message:       message,
+				Status:        models.NotificationPending,
+				MaxAttempts:   defaultMaxAttempts,
+				NextAttemptAt: now,
+			})
+		}
+	}
+
+	def.LastRunAt = &now
+	def.LastError = strings.Join(problems, "; ")
+	def.NextRunAt = NextReportRun(def, now)
+	if err := db.Save(def).Error; err != nil {
+		log.Printf("Failed to update report definition %s: %v", def.Name, err)
+	}
+	if def.LastError != "" {
+		return errors.New(def.LastError)
+	}
+	return nil
+}
+
+func mailReport(db *gorm.DB, def *models.ReportDefinition, generated *GeneratedReport, recipients []string) error {
+	name := channelForProvider(db, "mail")
+	if name == "" {
+		return fmt.Errorf("no mail channel configured for report recipients")
+	}
+	var channel models.AlertChannel
+	if err := db.Where("name = ?", name).First(&channel).Error; err != nil {
+		return err
+	}
+
+	subject := fmt.Sprintf("Availability report %s (%s)", def.Name, generated.Report.From.Format("2006-01-02"))
+	body := fmt.Sprintf("Uptime %.3f%%, %d incidents across %d hosts. The full report is attached.",
+		generated.Report.Total.UptimePercent, generated.Report.Total.Incidents, generated.Report.Total.Hosts)
+	return sendMailWithAttachment(channel, recipients, subject, body, generated.Filename, generated.ContentType, generated.Content)
+}
+
+// runDueReports runs every enabled definition whose next run has passed
+func runDueReports() {
+	db := database.DB
+
+	var defs []models.ReportDefinition
+	if err := db.Where("enabled = ? AND next_run_at <= ?", true, time.Now()).Find(&defs).Error; err != nil {
+		log.Println("Failed to load report definitions:", err)
+		return
+	}
+	for i := range defs {
+		if err := RunReportDefinition(&defs[i]); err != nil {
+			log.Printf("Report %s finished with problems: %v", defs[i].Name, err)
+		} else {
+			log.Printf("Report %s delivered", defs[i].Name)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package jobs
+
+import (
+	"alerting-app/config"
+	"alerting-app/database"
+	"alerting-app/events"
+	"alerting-app/models"
+	"log"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	defaultResultRetentionDays = 30
+	purgeBatchSize             = 5000
+)
+
+// recordCheckResult stores the raw outcome of a check
+func recordCheckResult(db *gorm.DB, check events.CheckCompleted) {
+	errText := check.Error
+	if len(errText) > 512 {
+		errText = errText[:512]
+	}
+
+	result := models.CheckResult{
+		HostID:     check.Host.ID,
+		CheckedAt:  check.CheckedAt,
+		Status:     check.Status,
+		LatencyMs:  check.LatencyMs,
+		StatusCode: check.StatusCode,
+		Error:      errText,
+	}
+	if err := db.Create(&result).Error; err != nil {
+		log.Printf("Failed to store check result for host %s: %v", check.Host.Name, err)
+	}
+}
+
+// ResultRetention reads RESULT_RETENTION_DAYS, defaulting to 30 days
+func ResultRetention() time.Duration {
+	days, err := strconv.Atoi(config.Config("RESULT_RETENTION_DAYS"))
+	if err != nil || days <= 0 {
+		days = defaultResultRetentionDays
+	}
+	return time.Duration(days) * 24 * time.Hour
+}
+
+// purgeCheckResults deletes raw results older than the retention period in small batches
+func purgeCheckResults() {
+	db := database.DB
+	cutoff := time.Now().Add(-ResultRetention())
+
+	// Never drop raw results that have not been rolled up yet
+	if until := rolledUpUntil(db); until.Before(cutoff) {
+		cutoff = until
+	}
+
+	var total int64
+	for {
+		result := db.Where("checked_at < ?", cutoff).Limit(purgeBatchSize).Delete(&models.CheckResult{})
+		if result.Error != nil {
+			log.Println("Failed to purge check results:", result.Error)
+			return
+		}
+		total += result.RowsAffected
+		if result.RowsAffected < purgeBatchSize {
+			break
+		}
+	}
+	if total > 0 {
+		log.Printf("Purged %d check results older than %s", total, cutoff.Format("2006-01-02 15:04:05"))
+	}
+}
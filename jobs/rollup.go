@@ -0,0 +1,200 @@
+package jobs
+
+import (
+	"alerting-app/config"
+	"alerting-app/database"
+	"alerting-app/models"
+	"log"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// rollupLevel describes one rollup resolution and how long it is kept
+type rollupLevel struct {
+	Name       string
+	Size       time.Duration
+	MaxBuckets int    // buckets processed per run, bounds catch-up work
+	RetainEnv  string // days, "" keeps forever
+	RetainDays int
+}
+
+// RollupLevels lists the resolutions from finest to coarsest
+var RollupLevels = []rollupLevel{
+	{Name: models.Rollup5m, Size: 5 * time.Minute, MaxBuckets: 288, RetainEnv: "ROLLUP_5M_RETENTION_DAYS", RetainDays: 90},
+	{Name: models.Rollup1h, Size: time.Hour, MaxBuckets: 48, RetainEnv: "ROLLUP_1H_RETENTION_DAYS", RetainDays: 400},
+	{Name: models.Rollup1d, Size: 24 * time.Hour, MaxBuckets: 7},
+}
+
+// Retention returns how long the level is kept, zero meaning forever
+func (level rollupLevel) Retention() time.Duration {
+	if level.RetainEnv == "" {
+		return 0
+	}
+	days, err := strconv.Atoi(config.Config(level.RetainEnv))
+	if err != nil || days <= 0 {
+		days = level.RetainDays
+	}
+	return time.Duration(days) * 24 * time.Hour
+}
+
+// bucketEnd is where the bucket starting at start ends; a day runs to the next local midnight,
+// which is 23 or 25 hours away across a DST change
+func (level rollupLevel) bucketEnd(start time.Time) time.Time {
+	if level.Size >= 24*time.Hour {
+		return start.AddDate(0, 0, 1)
+	}
+	return start.Add(level.Size)
+}
+
+// rollupGrace keeps a bucket open after it ends, since results are written asynchronously
+// and a check can take up to 20s
+const rollupGrace = 2 * time.Minute
+
+// rollupCheckResults aggregates every completed bucket that has not been rolled up yet
+func rollupCheckResults() {
+	db := database.DB
+	now := time.Now()
+
+	for _, level := range RollupLevels {
+		start, ok := nextRollupBucket(db, level)
+		if !ok {
+			continue
+		}
+		for i := 0; i < level.MaxBuckets; i++ {
+			end := level.bucketEnd(start)
+			if end.Add(rollupGrace).After(now) {
+				break
+			}
+			if err := rollupBucket(db, level, start, end); err != nil {
+				log.Printf("Failed to roll up %s bucket %s: %v", level.Name, start.Format("2006-01-02 15:04"), err)
+				break
+			}
+			start = end
+		}
+	}
+
+	purgeRollups(db)
+}
+
+// nextRollupBucket continues after the newest rollup, or starts at the oldest raw result
+func nextRollupBucket(db *gorm.DB, level rollupLevel) (time.Time, bool) {
+	var latest models.CheckRollup
+	err := db.Where("resolution = ?", level.Name).Order("bucket_start DESC").First(&latest).Error
+	if err == nil {
+		return level.bucketEnd(latest.BucketStart), true
+	}
+
+	var oldest models.CheckResult
+	if err := db.Order("checked_at ASC").First(&oldest).Error; err != nil {
+		return time.Time{}, false
+	}
+	return bucketStart(oldest.CheckedAt, level.Size), true
+}
+
+// bucketStart aligns t to the bucket grid; days are aligned to local midnight
+func bucketStart(t time.Time, size time.Duration) time.Time {
+	if size >= 24*time.Hour {
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	}
+	return t.Truncate(size)
+}
+
+// rollupBucket streams the raw results of one bucket and stores one rollup per host
+func rollupBucket(db *gorm.DB, level rollupLevel, start, end time.Time) error {
+	rows, err := db.Model(&models.CheckResult{}).
+		Select("host_id, status, latency_ms").
+		Where("checked_at >= ? AND checked_at < ?", start, end).
+		Order("host_id ASC").Rows()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	var rollups []models.CheckRollup
+	var latencies []float64
+	var current *models.CheckRollup
+
+	flush := func() {
+		if current == nil {
+			return
+		}
+		finishRollup(current, latencies)
+		rollups = append(rollups, *current)
+		latencies = latencies[:0]
+	}
+
+	for rows.Next() {
+		var hostID uint
+		var status string
+		var latency float64
+		if err := rows.Scan(&hostID, &status, &latency); err != nil {
+			return err
+		}
+		if current == nil || current.HostID != hostID {
+			flush()
+			current = &models.CheckRollup{HostID: hostID, Resolution: level.Name, BucketStart: start, MinLatency: math.MaxFloat64}
+		}
+		current.Checks++
+		if status == "up" {
+			current.UpCount++
+			// Latency of failed checks is mostly a timeout, so only successful checks count
+			latencies = append(latencies, latency)
+		}
+	}
+	flush()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	if len(rollups) == 0 {
+		// Store a marker so the watermark moves past empty buckets
+		rollups = append(rollups, models.CheckRollup{HostID: 0, Resolution: level.Name, BucketStart: start})
+	}
+	return db.Clauses(clause.OnConflict{UpdateAll: true}).CreateInBatches(&rollups, 500).Error
+}
+
+func finishRollup(rollup *models.CheckRollup, latencies []float64) {
+	rollup.UpRatio = float64(rollup.UpCount) / float64(rollup.Checks)
+	if len(latencies) == 0 {
+		rollup.MinLatency = 0
+		return
+	}
+
+	sort.Float64s(latencies)
+	var sum float64
+	for _, latency := range latencies {
+		sum += latency
+	}
+	rollup.MinLatency = latencies[0]
+	rollup.MaxLatency = latencies[len(latencies)-1]
+	rollup.AvgLatency = sum / float64(len(latencies))
+	rollup.P95Latency = latencies[int(math.Ceil(0.95*float64(len(latencies))))-1]
+}
+
+// purgeRollups removes rollups past their level's retention
+func purgeRollups(db *gorm.DB) {
+	for _, level := range RollupLevels {
+		retention := level.Retention()
+		if retention == 0 {
+			continue
+		}
+		cutoff := time.Now().Add(-retention)
+		if err := db.Where("resolution = ? AND bucket_start < ?", level.Name, cutoff).Delete(&models.CheckRollup{}).Error; err != nil {
+			log.Printf("Failed to purge %s rollups: %v", level.Name, err)
+		}
+	}
+}
+
+// rolledUpUntil is the end of the newest daily rollup; raw data before it is safe to purge
+func rolledUpUntil(db *gorm.DB) time.Time {
+	var latest models.CheckRollup
+	if err := db.Where("resolution = ?", models.Rollup1d).Order("bucket_start DESC").First(&latest).Error; err != nil {
+		return time.Time{}
+	}
+	return latest.BucketStart.AddDate(0, 0, 1)
+}
@@ -0,0 +1,303 @@
+package jobs
+
+import (
+	"alerting-app/models"
+	"bytes"
+	"fmt"
+	"html/template"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const statusPageDays = 90
+
+// Component and page states shown on status pages
+const (
+	StatusOperational = "operational"
+	StatusDegraded    = "degraded"
+	StatusOutage      = "outage"
+	StatusMaintenance = "maintenance"
+)
+
+// PublicStatusPage is what anonymous visitors see; it never contains host names or addresses
+type PublicStatusPage struct {
+	Title       string            `json:"title"`
+	Description string            `json:"description"`
+	Status      string            `json:"status"`
+	UpdatedAt   time.Time         `json:"updated_at"`
+	Components  []PublicComponent `json:"components"`
+	Incidents   []PublicIncident  `json:"incidents"`
+}
+
+type PublicComponent struct {
+	Name   string      `json:"name"`
+	Status string      `json:"status"`
+	Uptime *float64    `json:"uptime_90d"` // percent, nil without data
+	Days   []UptimeDay `json:"days"`
+}
+
+// UptimeDay is one bar of the 90-day uptime history
+type UptimeDay struct {
+	Date   string   `json:"date"`
+	Uptime *float64 `json:"uptime"` // percent, nil without data
+}
+
+type PublicIncident struct {
+	Component    string     `json:"component"`
+	StartedAt    time.Time  `json:"started_at"`
+	Acknowledged bool       `json:"acknowledged"`
+	Note         string     `json:"note"`
+	ResolvedAt   *time.Time `json:"resolved_at,omitempty"`
+}
+
+// BuildStatusPage resolves the page components to hosts and computes their status and history
+func BuildStatusPage(db *gorm.DB, page *models.StatusPage) (*PublicStatusPage, error) {
+	now := time.Now()
+	today := bucketStart(now, 24*time.Hour)
+	from := today.AddDate(0, 0, -(statusPageDays - 1))
+
+	result := &PublicStatusPage{
+		Title:       page.Title,
+		Description: page.Description,
+		Status:      StatusOperational,
+		UpdatedAt:   now,
+	}
+
+	componentOf := map[uint]string{}
+	for _, component := range page.Components {
+		var hosts []models.Host
+		query := db.Where("is_active = ?", true)
+		switch {
+		case component.HostID != nil:
+			query = query.Where("id = ?", *component.HostID)
+		case component.GroupID != nil:
+			var err error
+			if query, err = WhereGroup(db, query, *component.GroupID); err != nil {
+				return nil, err
+			}
+		default:
+			query = query.Where("device_type_name = ?", component.DeviceTypeName)
+		}
+		if err := query.Find(&hosts).Error; err != nil {
+			return nil, err
+		}
+
+		public := PublicComponent{Name: component.DisplayName}
+		if component.GroupID != nil {
+			// Same rollup as the group status endpoint
+			var group models.HostGroup
+			if err := db.First(&group, *component.GroupID).Error; err != nil {
+				return nil, err
+			}
+			status, err := BuildGroupStatus(db, &group)
+			if err != nil {
+				return nil, err
+			}
+			public.Status = status.Status
+		} else {
+			public.Status = componentStatus(db, hosts, now)
+		}
+		var ids []uint
+		for _, host := range hosts {
+			ids = append(ids, host.ID)
+			if _, ok := componentOf[host.ID]; !ok {
+				componentOf[host.ID] = component.DisplayName
+			}
+		}
+
+		days, err := dailyUptime(db, ids, from, now)
+		if err != nil {
+			return nil, err
+		}
+		var up, checks int64
+		for day := from; !day.After(today); day = day.AddDate(0, 0, 1) {
+			bar := UptimeDay{Date: day.Format("2006-01-02")}
+			if counts, ok := days[bar.Date]; ok && counts[1] > 0 {
+				pct := float64(counts[0]) / float64(counts[1]) * 100
+				bar.Uptime = &pct
+				up += counts[0]
+				checks += counts[1]
+			}
+			public.Days = append(public.Days, bar)
+		}
+		if checks > 0 {
+			pct := float64(up) / float64(checks) * 100
+			public.Uptime = &pct
+		}
+
+		result.Components = append(result.Components, public)
+		result.Status = worseStatus(result.Status, public.Status)
+	}
+
+	if len(componentOf) > 0 {
+		ids := make([]uint, 0, len(componentOf))
+		for id := range componentOf {
+			ids = append(ids, id)
+		}
+		var incidents []models.Incident
+		if err := db.Where("host_id IN ? AND status = ?", ids, models.IncidentOpen).
+			Order("opened_at DESC").Find(&incidents).Error; err != nil {
+			return nil, err
+		}
+		for _, incident := range incidents {
+			result.Incidents = append(result.Incidents, PublicIncident{
+				Component:    componentOf[incident.HostID],
+				StartedAt:    incident.OpenedAt,
+				Acknowledged: incident.AcknowledgedAt != nil,
+				Note:         incident.PublicNote,
+			})
+		}
+	}
+	return result, nil
+}
+
+// componentStatus is an outage when every host is down and degraded when only some are
+func componentStatus(db *gorm.DB, hosts []models.Host, now time.Time) string {
+	down, maintenance := 0, 0
+	for i := range hosts {
+		if inMaintenance(db, &hosts[i], now) {
+			maintenance++
+		} else if hosts[i].AlertStatus {
+			down++
+		}
+	}
+	switch {
+	case len(hosts) == 0:
+		return StatusOperational
+	case maintenance == len(hosts):
+		return StatusMaintenance
+	case down == 0:
+		return StatusOperational
+	case down+maintenance == len(hosts):
+		return StatusOutage
+	default:
+		return StatusDegraded
+	}
+}
+
+var statusRank = map[string]int{StatusOperational: 0, StatusMaintenance: 1, StatusDegraded: 2, StatusOutage: 3}
+
+func worseStatus(a, b string) string {
+	if statusRank[b] > statusRank[a] {
+		return b
+	}
+	return a
+}
+
+// dailyUptime returns [up, checks] per local day, from daily rollups and raw results for days not rolled up yet
+func dailyUptime(db *gorm.DB, hostIDs []uint, from, to time.Time) (map[string][2]int64, error) {
+	days := map[string][2]int64{}
+	if len(hostIDs) == 0 {
+		return days, nil
+	}
+
+	var rollups []struct {
+		BucketStart time.Time
+		Up          int64
+		Checks      int64
+	}
+	if err := db.Model(&models.CheckRollup{}).
+		Select("bucket_start, SUM(up_count) AS up, SUM(checks) AS checks").
+		Where("resolution = ? AND host_id IN ? AND bucket_start >= ?", models.Rollup1d, hostIDs, from).
+		Group("bucket_start").Scan(&rollups).Error; err != nil {
+		return nil, err
+	}
+	for _, rollup := range rollups {
+		days[rollup.BucketStart.In(time.Local).Format("2006-01-02")] = [2]int64{rollup.Up, rollup.Checks}
+	}
+
+	rawFrom := rolledUpUntil(db)
+	if rawFrom.Before(from) {
+		rawFrom = from
+	}
+	for day := bucketStart(rawFrom, 24*time.Hour); day.Before(to); day = day.AddDate(0, 0, 1) {
+		var counts struct {
+			Up     int64
+			Checks int64
+		}
+		if err := db.Model(&models.CheckResult{}).
+			Select("COALESCE(SUM(CASE WHEN status = 'up' THEN 1 ELSE 0 END), 0) AS up, COUNT(*) AS checks").
+			Where("host_id IN ? AND checked_at >= ? AND checked_at < ?", hostIDs, day, day.AddDate(0, 0, 1)).
+			Scan(&counts).Error; err != nil {
+			return nil, err
+		}
+		if counts.Checks > 0 {
+			days[day.Format("2006-01-02")] = [2]int64{counts.Up, counts.Checks}
+		}
+	}
+	return days, nil
+}
+
+var statusColors = map[string]string{
+	StatusOperational: "#2e7d32",
+	StatusDegraded:    "#f9a825",
+	StatusOutage:      "#c62828",
+	StatusMaintenance: "#1565c0",
+}
+
+var statusLabels = map[string]string{
+	StatusOperational: "All systems operational",
+	StatusDegraded:    "Partial outage",
+	StatusOutage:      "Major outage",
+	StatusMaintenance: "Under maintenance",
+}
+
+var statusPageTemplate = template.Must(template.New("status").Funcs(template.FuncMap{
+	"color": func(status string) string { return statusColors[status] },
+	"label": func(status string) string { return statusLabels[status] },
+	"uptime": func(v *float64) string {
+		if v == nil {
+			return "no data"
+		}
+		return fmt.Sprintf("%.2f%%", *v)
+	},
+	"barColor": func(v *float64) string {
+		switch {
+		case v == nil:
+			return "#ddd"
+		case *v >= 99.9:
+			return statusColors[StatusOperational]
+		case *v >= 95:
+			return statusColors[StatusDegraded]
+		default:
+			return statusColors[StatusOutage]
+		}
+	},
+	"date": func(t time.Time) string { return t.Format("2006-01-02 15:04") },
+}).Parse(`<!DOCTYPE html>
+<html><head><meta charset="utf-8"><meta name="viewport" content="width=device-width,initial-scale=1">
+<meta http-equiv="refresh" content="60"><title>{{.Title}}</title>
+<style>
+body{font-family:Arial,Helvetica,sans-serif;color:#222;max-width:860px;margin:24px auto;padding:0 12px}
+h1{font-size:24px;margin-bottom:4px}.muted{color:#777;font-size:13px}
+.banner{color:#fff;border-radius:6px;padding:14px 18px;font-size:18px;margin:18px 0}
+.component{border:1px solid #e0e0e0;border-radius:6px;padding:12px 16px;margin-bottom:10px}
+.row{display:flex;justify-content:space-between}
+.bars{display:flex;gap:1px;margin-top:8px;height:28px}.bars span{flex:1;border-radius:2px}
+.incident{border-left:4px solid #c62828;padding:6px 12px;margin-bottom:10px}
+</style></head><body>
+<h1>{{.Title}}</h1>
+{{if .Description}}<div class="muted">{{.Description}}</div>{{end}}
+<div class="banner" style="background:{{color .Status}}">{{label .Status}}</div>
+{{if .Incidents}}<h2>Active incidents</h2>
+{{range .Incidents}}<div class="incident"><b>{{.Component}}</b> <span class="muted">since {{date .StartedAt}}{{if .Acknowledged}}, being worked on{{end}}</span>
+{{if .Note}}<div>{{.Note}}</div>{{end}}</div>
+{{end}}{{end}}
+{{range .Components}}<div class="component">
+<div class="row"><b>{{.Name}}</b><span style="color:{{color .Status}}">{{.Status}}</span></div>
+<div class="bars">{{range .Days}}<span title="{{.Date}}: {{uptime .Uptime}}" style="background:{{barColor .Uptime}}"></span>{{end}}</div>
+<div class="row muted"><span>90 days ago</span><span>{{uptime .Uptime}} uptime</span><span>Today</span></div>
+</div>
+{{end}}
+<div class="muted">Updated {{date .UpdatedAt}}</div>
+</body></html>`))
+
+// RenderStatusPageHTML renders the public page as a standalone HTML document
+func RenderStatusPageHTML(page *PublicStatusPage) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := statusPageTemplate.Execute(&buf, page); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
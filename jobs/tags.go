@@ -0,0 +1,193 @@
+package jobs
+
+import (
+	"alerting-app/models"
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// tagMatch is one term of a tag selector; an empty Value with AnyValue matches any value
+type tagMatch struct {
+	Key      string
+	Value    string
+	AnyValue bool
+}
+
+// ParseTagSelector parses "site=ub-hq,floor=3"; a bare key only requires the tag to exist
+func ParseTagSelector(selector string) ([]tagMatch, error) {
+	var matches []tagMatch
+	for _, term := range splitList(selector) {
+		key, value, hasValue := strings.Cut(term, "=")
+		key = strings.TrimSpace(key)
+		if key == "" {
+			return nil, fmt.Errorf("invalid tag selector term %q", term)
+		}
+		matches = append(matches, tagMatch{Key: key, Value: strings.TrimSpace(value), AnyValue: !hasValue})
+	}
+	return matches, nil
+}
+
+// tagsMatch reports whether the tags satisfy every term of the selector
+func tagsMatch(selector []tagMatch, tags []models.HostTag) bool {
+	for _, term := range selector {
+		found := false
+		for _, tag := range tags {
+			if tag.Key == term.Key && (term.AnyValue || tag.Value == term.Value) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// hostTags loads the tags of a host
+func hostTags(db *gorm.DB, hostID uint) []models.HostTag {
+	var tags []models.HostTag
+	db.Where("host_id = ?", hostID).Find(&tags)
+	return tags
+}
+
+// WhereTags restricts a hosts query to the hosts matching the selector
+func WhereTags(db *gorm.DB, query *gorm.DB, selector []tagMatch) *gorm.DB {
+	for _, term := range selector {
+		sub := db.Model(&models.HostTag{}).Select("host_id").Where("tag_key = ?", term.Key)
+		if !term.AnyValue {
+			sub = sub.Where("tag_value = ?", term.Value)
+		}
+		query = query.Where("id IN (?)", sub)
+	}
+	return query
+}
+
+// GroupAndDescendants returns the group id followed by the ids of every group below it
+func GroupAndDescendants(db *gorm.DB, groupID uint) ([]uint, error) {
+	var groups []models.HostGroup
+	if err := db.Select("id", "parent_id").Find(&groups).Error; err != nil {
+		return nil, err
+	}
+	children := map[uint][]uint{}
+	for _, group := range groups {
+		if group.ParentID != nil {
+			children[*group.ParentID] = append(children[*group.ParentID], group.ID)
+		}
+	}
+
+	ids := []uint{groupID}
+	seen := map[uint]bool{groupID: true}
+	for i := 0; i < len(ids); i++ {
+		for _, child := range children[ids[i]] {
+			if !seen[child] {
+				seen[child] = true
+				ids = append(ids, child)
+			}
+		}
+	}
+	return ids, nil
+}
+
+// WhereGroup restricts a hosts query to the group and its subgroups
+func WhereGroup(db *gorm.DB, query *gorm.DB, groupID uint) (*gorm.DB, error) {
+	ids, err := GroupAndDescendants(db, groupID)
+	if err != nil {
+		return nil, err
+	}
+	return query.Where("group_id IN ?", ids), nil
+}
+
+// hostInGroup reports whether the host belongs to the group or one of its subgroups
+func hostInGroup(db *gorm.DB, host *models.Host, groupID uint) bool {
+	if host.GroupID == nil {
+		return false
+	}
+	ids, err := GroupAndDescendants(db, groupID)
+	if err != nil {
+		return false
+	}
+	for _, id := range ids {
+		if id == *host.GroupID {
+			return true
+		}
+	}
+	return false
+}
+
+// hostMatches reports whether the host satisfies an optional group and tag selector
+func hostMatches(db *gorm.DB, host *models.Host, groupID *uint, selector string) bool {
+	if groupID != nil && !hostInGroup(db, host, *groupID) {
+		return false
+	}
+	if selector == "" {
+		return true
+	}
+	terms, err := ParseTagSelector(selector)
+	if err != nil {
+		return false
+	}
+	return tagsMatch(terms, hostTags(db, host.ID))
+}
+
+// routedChannels returns the channels of every alert route matching the host
+func routedChannels(db *gorm.DB, host *models.Host) []string {
+	var routes []models.AlertRoute
+	if err := db.Find(&routes).Error; err != nil {
+		return nil
+	}
+	var channels []string
+	for _, route := range routes {
+		if route.ChannelName != "" && hostMatches(db, host, route.GroupID, route.TagSelector) {
+			channels = append(channels, route.ChannelName)
+		}
+	}
+	return channels
+}
+
+// GroupStatus rolls up the state of every active host in a group and its subgroups
+type GroupStatus struct {
+	GroupID uint   `json:"group_id"`
+	Name    string `json:"name"`
+	Status  string `json:"status"` // see status page states
+	Hosts   int    `json:"hosts"`
+	Up      int    `json:"up"`
+	Down    int    `json:"down"`
+	Pending int    `json:"pending"`
+	Paused  int    `json:"paused"`
+}
+
+// BuildGroupStatus counts the hosts of the group by state
+func BuildGroupStatus(db *gorm.DB, group *models.HostGroup) (*GroupStatus, error) {
+	query, err := WhereGroup(db, db.Model(&models.Host{}), group.ID)
+	if err != nil {
+		return nil, err
+	}
+	var hosts []models.Host
+	if err := query.Find(&hosts).Error; err != nil {
+		return nil, err
+	}
+
+	status := &GroupStatus{GroupID: group.ID, Name: group.Name}
+	var active []models.Host
+	for _, host := range hosts {
+		status.Hosts++
+		switch {
+		case !host.IsActive:
+			status.Paused++
+			continue
+		case host.AlertStatus:
+			status.Down++
+		case host.IsPending:
+			status.Pending++
+		default:
+			status.Up++
+		}
+		active = append(active, host)
+	}
+	status.Status = componentStatus(db, active, time.Now())
+	return status, nil
+}
@@ -0,0 +1,383 @@
+package jobs
+
+import (
+	"alerting-app/config"
+	"alerting-app/database"
+	"alerting-app/models"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// The bot uses the first telegram channel: Config1 is the API base
+// (e.g. https://api.telegram.org/bot, or a local stand-in), Config2 the token.
+// Only chats in TELEGRAM_ALLOWED_CHATS (comma separated) may talk to it;
+// the channel's own chat (Config3) is always allowed.
+
+const telegramPollTimeout = 30 // seconds
+
+type telegramUpdate struct {
+	UpdateID      int64            `json:"update_id"`
+	Message       *telegramMessage `json:"message"`
+	CallbackQuery *struct {
+		ID      string           `json:"id"`
+		From    telegramUser     `json:"from"`
+		Message *telegramMessage `json:"message"`
+		Data    string           `json:"data"`
+	} `json:"callback_query"`
+}
+
+type telegramMessage struct {
+	Chat struct {
+		ID int64 `json:"id"`
+	} `json:"chat"`
+	From telegramUser `json:"from"`
+	Text string       `json:"text"`
+}
+
+type telegramUser struct {
+	Username  string `json:"username"`
+	FirstName string `json:"first_name"`
+}
+
+func (u telegramUser) name() string {
+	if u.Username != "" {
+		return "telegram:" + u.Username
+	}
+	return "telegram:" + u.FirstName
+}
+
+// StartTelegramBot starts long polling when TELEGRAM_BOT_ENABLED is "true"
+func StartTelegramBot() {
+	if config.Config("TELEGRAM_BOT_ENABLED") != "true" {
+		return
+	}
+	fmt.Println("Starting Telegram bot...")
+	go runTelegramBot()
+}
+
+func runTelegramBot() {
+	var offset int64
+	for {
+		channel, ok := telegramBotChannel()
+		if !ok {
+			time.Sleep(time.Minute)
+			continue
+		}
+
+		updates, err := telegramGetUpdates(channel, offset)
+		if err != nil {
+			log.Println("Telegram bot polling failed:", err)
+			time.Sleep(5 * time.Second)
+			continue
+		}
+
+		allowed := telegramAllowedChats(channel)
+		for _, update := range updates {
+			offset = update.UpdateID + 1
+			handleTelegramUpdate(channel, allowed, update)
+		}
+	}
+}
+
+func telegramBotChannel() (models.AlertChannel, bool) {
+	var channel models.AlertChannel
+	name := channelForProvider(database.DB, "telegram")
+	if name == "" {
+		return channel, false
+	}
+	if err := database.DB.Where("name = ?", name).First(&channel).Error; err != nil {
+		return channel, false
+	}
+	return channel, channel.Config1 != "" && channel.Config2 != ""
+}
+
+func telegramAllowedChats(channel models.AlertChannel) map[string]bool {
+	allowed := map[string]bool{}
+	for _, chat := range splitList(config.Config("TELEGRAM_ALLOWED_CHATS")) {
+		allowed[chat] = true
+	}
+	if channel.Config3 != "" {
+		allowed[channel.Config3] = true
+	}
+	return allowed
+}
+
+func telegramGetUpdates(channel models.AlertChannel, offset int64) ([]telegramUpdate, error) {
+	// The request blocks for up to telegramPollTimeout, so it gets its own client timeout
+	body, err := telegramCallTimeout(channel.Config1, channel.Config2, "getUpdates", map[string]interface{}{
+		"offset":          offset,
+		"timeout":         telegramPollTimeout,
+		"allowed_updates": []string{"message", "callback_query"},
+	}, (telegramPollTimeout+10)*time.Second)
+	if err != nil {
+		return nil, err
+	}
+
+	var response struct {
+		OK     bool             `json:"ok"`
+		Result []telegramUpdate `json:"result"`
+	}
+	if err := json.Unmarshal([]byte(body), &response); err != nil {
+		return nil, fmt.Errorf("invalid getUpdates response: %v", err)
+	}
+	if !response.OK {
+		return nil, fmt.Errorf("getUpdates returned not ok: %s", body)
+	}
+	return response.Result, nil
+}
+
+func handleTelegramUpdate(channel models.AlertChannel, allowed map[string]bool, update telegramUpdate) {
+	switch {
+	case update.Message != nil:
+		chatID := strconv.FormatInt(update.Message.Chat.ID, 10)
+		if !allowed[chatID] {
+			log.Printf("Ignoring Telegram message from chat %s", chatID)
+			return
+		}
+		reply := runTelegramCommand(update.Message.Text, update.Message.From.name())
+		if reply != "" {
+			telegramReply(channel, chatID, reply)
+		}
+	case update.CallbackQuery != nil && update.CallbackQuery.Message != nil:
+		query := update.CallbackQuery
+		chatID := strconv.FormatInt(query.Message.Chat.ID, 10)
+		if !allowed[chatID] {
+			return
+		}
+		reply := runTelegramCallback(query.Data, query.From.name())
+		telegramCall(channel.Config1, channel.Config2, "answerCallbackQuery", map[string]interface{}{
+			"callback_query_id": query.ID,
+			"text":              reply,
+		})
+		telegramReply(channel, chatID, reply)
+	}
+}
+
+func telegramReply(channel models.AlertChannel, chatID, text string) {
+	if _, err := sendTelegramAlert(text, channel.Config1, channel.Config2, chatID); err != nil {
+		log.Println("Failed to reply on Telegram:", err)
+	}
+}
+
+// runTelegramCommand executes a bot command and returns the reply text
+func runTelegramCommand(text, by string) string {
+	fields := strings.Fields(text)
+	if len(fields) == 0 || !strings.HasPrefix(fields[0], "/") {
+		return ""
+	}
+	// Commands may be addressed as /status@botname in groups
+	command := strings.SplitN(fields[0], "@", 2)[0]
+	args := fields[1:]
+	db := database.DB
+
+	switch command {
+	case "/status":
+		return telegramStatus(db)
+	case "/down":
+		return telegramDownHosts(db)
+	case "/host":
+		if len(args) < 1 {
+			return "Usage: /host <name>"
+		}
+		return telegramHostDetails(db, strings.Join(args, " "))
+	case "/ack":
+		if len(args) < 1 {
+			return "Usage: /ack <incident>"
+		}
+		return telegramAck(db, args[0], by)
+	case "/mute":
+		if len(args) < 2 {
+			return "Usage: /mute <host> <duration>, e.g. /mute cam-01 2h"
+		}
+		host, err := findHostByName(db, strings.Join(args[:len(args)-1], " "))
+		if err != nil {
+			return err.Error()
+		}
+		return telegramMute(db, host, args[len(args)-1])
+	case "/check":
+		if len(args) < 1 {
+			return "Usage: /check <host>"
+		}
+		host, err := findHostByName(db, strings.Join(args, " "))
+		if err != nil {
+			return err.Error()
+		}
+		if runHostCheck(db, host) {
+			return fmt.Sprintf("%s (%s) is DOWN (failed checks: %d/%d)", host.Name, host.IP, host.RetryCount, host.NumOfRetry)
+		}
+		return fmt.Sprintf("%s (%s) is UP", host.Name, host.IP)
+	case "/start", "/help":
+		return "Commands: /status, /down, /host <name>, /ack <incident>, /mute <host> <duration>, /check <host>"
+	default:
+		return "Unknown command " + command + ", try /help"
+	}
+}
+
+// runTelegramCallback handles the inline buttons attached to alert messages
+func runTelegramCallback(data, by string) string {
+	db := database.DB
+	parts := strings.Split(data, ":")
+	switch {
+	case len(parts) == 2 && parts[0] == "ack":
+		return telegramAck(db, parts[1], by)
+	case len(parts) == 3 && parts[0] == "mute":
+		var host models.Host
+		if err := db.First(&host, parts[1]).Error; err != nil {
+			return "Host not found"
+		}
+		return telegramMute(db, &host, parts[2])
+	default:
+		return "Unknown action"
+	}
+}
+
+// telegramAlertButtons adds acknowledge and mute buttons to down alerts
+func telegramAlertButtons(notification *models.Notification) map[string]interface{} {
+	var buttons []map[string]string
+	if notification.IncidentID != nil {
+		buttons = append(buttons, map[string]string{
+			"text":          "Acknowledge",
+			"callback_data": fmt.Sprintf("ack:%d", *notification.IncidentID),
+		})
+	}
+	if notification.HostID != 0 && notification.Event != "up" {
+		buttons = append(buttons, map[string]string{
+			"text":          "Mute 1h",
+			"callback_data": fmt.Sprintf("mute:%d:1h", notification.HostID),
+		})
+	}
+	if len(buttons) == 0 {
+		return nil
+	}
+	return map[string]interface{}{
+		"inline_keyboard": [][]map[string]string{buttons},
+	}
+}
+
+func telegramStatus(db *gorm.DB) string {
+	var hosts []models.Host
+	if err := db.Find(&hosts).Error; err != nil {
+		return "Failed to load hosts: " + err.Error()
+	}
+
+	up, down, pending, paused := 0, 0, 0, 0
+	for _, host := range hosts {
+		switch {
+		case !host.IsActive:
+			paused++
+		case host.AlertStatus:
+			down++
+		case host.IsPending:
+			pending++
+		default:
+			up++
+		}
+	}
+
+	var openIncidents int64
+	db.Model(&models.Incident{}).Where("status = ?", models.IncidentOpen).Count(&openIncidents)
+	return fmt.Sprintf("Hosts: %d up, %d down, %d pending, %d paused\nOpen incidents: %d", up, down, pending, paused, openIncidents)
+}
+
+func telegramDownHosts(db *gorm.DB) string {
+	var hosts []models.Host
+	if err := db.Where("alert_status = ? AND is_active = ?", true, true).Order("name ASC").Find(&hosts).Error; err != nil {
+		return "Failed to load hosts: " + err.Error()
+	}
+	if len(hosts) == 0 {
+		return "All hosts are up"
+	}
+
+	var text strings.Builder
+	fmt.Fprintf(&text, "%d hosts down:\n", len(hosts))
+	for _, host := range hosts {
+		fmt.Fprintf(&text, "- %s (%s) since %s", host.Name, host.IP, host.LastAlert)
+		if incident := openIncidentFor(db, host.ID); incident != nil {
+			fmt.Fprintf(&text, ", incident #%d", incident.ID)
+			if incident.AcknowledgedAt != nil {
+				text.WriteString(" (acked)")
+			}
+		}
+		text.WriteString("\n")
+	}
+	return strings.TrimRight(text.String(), "\n")
+}
+
+func telegramHostDetails(db *gorm.DB, name string) string {
+	host, err := findHostByName(db, name)
+	if err != nil {
+		return err.Error()
+	}
+
+	state := "UP"
+	switch {
+	case !host.IsActive:
+		state = "PAUSED"
+	case host.AlertStatus:
+		state = "DOWN since " + host.LastAlert
+	case host.IsPending:
+		state = fmt.Sprintf("PENDING (%d/%d failed checks)", host.RetryCount, host.NumOfRetry)
+	}
+
+	text := fmt.Sprintf("%s (%s)\nState: %s\nType: %s\nInterval: %d min\nLast checked: %s",
+		host.Name, host.IP, state, host.DeviceTypeName, host.Interval, host.LastCheckedDate.Format("2006-01-02 15:04:05"))
+	if hostMuted(host) {
+		text += "\nMuted until: " + host.MutedUntil.Format("2006-01-02 15:04:05")
+	}
+	return text
+}
+
+func telegramAck(db *gorm.DB, id, by string) string {
+	var incident models.Incident
+	if err := db.First(&incident, strings.TrimPrefix(id, "#")).Error; err != nil {
+		return "Incident " + id + " not found"
+	}
+	if err := AcknowledgeIncident(&incident, by); err != nil {
+		return err.Error()
+	}
+	return fmt.Sprintf("Incident #%d for %s acknowledged by %s", incident.ID, incident.HostName, incident.AcknowledgedBy)
+}
+
+func telegramMute(db *gorm.DB, host *models.Host, value string) string {
+	duration, err := time.ParseDuration(value)
+	if err != nil || duration <= 0 {
+		return "Invalid duration " + value + ", use e.g. 30m or 2h"
+	}
+	until := time.Now().Add(duration)
+	if err := db.Model(host).Updates(map[string]interface{}{"muted_until": until, "version": models.NextHostVersion}).Error; err != nil {
+		return "Failed to mute host: " + err.Error()
+	}
+	return fmt.Sprintf("%s muted until %s", host.Name, until.Format("2006-01-02 15:04:05"))
+}
+
+// findHostByName matches the exact name first, then a unique partial match
+func findHostByName(db *gorm.DB, name string) (*models.Host, error) {
+	var host models.Host
+	err := db.Where("name = ?", name).First(&host).Error
+	if err == nil {
+		return &host, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	var hosts []models.Host
+	if err := db.Where("name LIKE ?", "%"+name+"%").Limit(2).Find(&hosts).Error; err != nil {
+		return nil, err
+	}
+	switch len(hosts) {
+	case 0:
+		return nil, fmt.Errorf("host %q not found", name)
+	case 1:
+		return &hosts[0], nil
+	default:
+		return nil, fmt.Errorf("host %q is ambiguous, be more specific", name)
+	}
+}
@@ -0,0 +1,308 @@
+package jobs
+
+import (
+	"alerting-app/models"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ReportScope limits a report to some hosts; empty fields mean no restriction
+type ReportScope struct {
+	HostIDs     []uint `json:"host_ids"`
+	DeviceType  string `json:"device_type"`
+	GroupID     *uint  `json:"group_id"`
+	TagSelector string `json:"tag_selector"`
+}
+
+// scopeHosts returns a hosts query limited to the scope
+func scopeHosts(db *gorm.DB, scope ReportScope) (*gorm.DB, error) {
+	query := db.Model(&models.Host{})
+	if len(scope.HostIDs) > 0 {
+		query = query.Where("id IN ?", scope.HostIDs)
+	}
+	if scope.DeviceType != "" {
+		query = query.Where("device_type_name = ?", scope.DeviceType)
+	}
+	if scope.GroupID != nil {
+		var err error
+		if query, err = WhereGroup(db, query, *scope.GroupID); err != nil {
+			return nil, err
+		}
+	}
+	if scope.TagSelector != "" {
+		terms, err := ParseTagSelector(scope.TagSelector)
+		if err != nil {
+			return nil, err
+		}
+		query = WhereTags(db, query, terms)
+	}
+	return query, nil
+}
+
+// UptimeRow is the availability of one host or one group of hosts
+type UptimeRow struct {
+	Key             string   `json:"key"`
+	Name            string   `json:"name"`
+	Hosts           int      `json:"hosts"`
+	UptimePercent   float64  `json:"uptime_percent"`
+	Incidents       int      `json:"incidents"`
+	DowntimeMinutes float64  `json:"downtime_minutes"`
+	MaintenanceMins float64  `json:"maintenance_minutes"`
+	MTTRMinutes     *float64 `json:"mttr_minutes"`
+	MTBFMinutes     *float64 `json:"mtbf_minutes"`
+	SLATarget       float64  `json:"sla_target,omitempty"`
+	SLABreached     bool     `json:"sla_breached"`
+	BreachedHosts   int      `json:"breached_hosts,omitempty"`
+
+	monitored time.Duration
+	downtime  time.Duration
+}
+
+// UptimeReport holds the rows of a report over [From, To)
+type UptimeReport struct {
+	From    time.Time   `json:"from"`
+	To      time.Time   `json:"to"`
+	GroupBy string      `json:"group_by"`
+	Rows    []UptimeRow `json:"rows"`
+	Total   UptimeRow   `json:"total"`
+}
+
+// BuildUptimeReport computes uptime, incidents, MTTR and MTBF from the host history state changes,
+// excluding maintenance windows. groupBy is "host", "device_type", "group" or "tag:<key>".
+func BuildUptimeReport(db *gorm.DB, from, to time.Time, groupBy string, scope ReportScope) (*UptimeReport, error) {
+	query, err := scopeHosts(db, scope)
+	if err != nil {
+		return nil, err
+	}
+	var hosts []models.Host
+	if err := query.Order("name ASC").Find(&hosts).Error; err != nil {
+		return nil, err
+	}
+
+	hostRows := make([]UptimeRow, 0, len(hosts))
+	for i := range hosts {
+		row, err := hostUptime(db, &hosts[i], from, to)
+		if err != nil {
+			return nil, err
+		}
+		hostRows = append(hostRows, row)
+	}
+
+	report := &UptimeReport{From: from, To: to, GroupBy: groupBy}
+	switch groupBy {
+	case "", "host":
+		report.GroupBy = "host"
+		report.Rows = hostRows
+	case "device_type", "group":
+		names := map[string]string{}
+		if groupBy == "group" {
+			var groups []models.HostGroup
+			db.Find(&groups)
+			for _, group := range groups {
+				names[fmt.Sprint(group.ID)] = group.Name
+			}
+		}
+		report.Rows = groupUptimeRows(groupBy, hostRows, func(i int) string {
+			if groupBy == "device_type" {
+				return hosts[i].DeviceTypeName
+			}
+			if hosts[i].GroupID == nil {
+				return ""
+			}
+			return fmt.Sprint(*hosts[i].GroupID)
+		}, names)
+	case "tag":
+		return nil, fmt.Errorf("group_by tag needs a key, e.g. tag:site")
+	default:
+		tagKey, ok := strings.CutPrefix(groupBy, "tag:")
+		if !ok || tagKey == "" {
+			return nil, fmt.Errorf("unsupported group_by %q", groupBy)
+		}
+		values := map[uint]string{}
+		var tags []models.HostTag
+		if err := db.Where("tag_key = ?", tagKey).Find(&tags).Error; err != nil {
+			return nil, err
+		}
+		for _, tag := range tags {
+			values[tag.HostID] = tag.Value
+		}
+		report.Rows = groupUptimeRows("tag:"+tagKey, hostRows, func(i int) string { return values[hosts[i].ID] }, nil)
+	}
+
+	report.Total = combineUptime("total", "All hosts", hostRows)
+	return report, nil
+}
+
+// groupUptimeRows combines the host rows by the key keyOf returns for each host;
+// names maps keys to display names, the key itself is shown otherwise
+func groupUptimeRows(prefix string, hostRows []UptimeRow, keyOf func(i int) string, names map[string]string) []UptimeRow {
+	groups := map[string][]UptimeRow{}
+	for i, row := range hostRows {
+		key := keyOf(i)
+		groups[key] = append(groups[key], row)
+	}
+	var rows []UptimeRow
+	for key, members := range groups {
+		name := key
+		if display, ok := names[key]; ok {
+			name = display
+		}
+		if name == "" {
+			name = "(none)"
+		}
+		rows = append(rows, combineUptime(prefix+":"+key, name, members))
+	}
+	sort.Slice(rows, func(i, j int) bool { return rows[i].Name < rows[j].Name })
+	return rows
+}
+
+// hostSLATarget is the host's SLA target, or its device type's when the host has none
+func hostSLATarget(db *gorm.DB, host *models.Host) float64 {
+	if host.SLATarget > 0 || host.DeviceTypeName == "" {
+		return host.SLATarget
+	}
+	var deviceType models.DeviceType
+	if err := db.Select("sla_target").Where("dev_type = ?", host.DeviceTypeName).First(&deviceType).Error; err != nil {
+		return 0
+	}
+	return deviceType.SLATarget
+}
+
+// hostUptime replays the host's down/up history over the period
+func hostUptime(db *gorm.DB, host *models.Host, from, to time.Time) (UptimeRow, error) {
+	row := UptimeRow{
+		Key:       fmt.Sprintf("host:%d", host.ID),
+		Name:      host.Name,
+		Hosts:     1,
+		SLATarget: hostSLATarget(db, host),
+	}
+
+	// Hosts are only accountable from the moment they were added
+	start := from
+	if host.CreatedAt.After(start) {
+		start = host.CreatedAt
+	}
+	if !to.After(start) {
+		row.UptimePercent = 100
+		return row, nil
+	}
+
+	// State at the beginning of the period
+	var before models.HostHistory
+	down := false
+	if err := db.Where("host_id = ? AND checked_at < ?", host.ID, start).Order("checked_at DESC").First(&before).Error; err == nil {
+		down = before.Status == "down"
+	}
+
+	var history []models.HostHistory
+	if err := db.Where("host_id = ? AND checked_at >= ? AND checked_at < ?", host.ID, start, to).
+		Order("checked_at ASC").Find(&history).Error; err != nil {
+		return row, err
+	}
+
+	// An outage carried over from before the period was counted as an incident in an earlier one
+	var outages []interval
+	var fresh []bool
+	downSince, carried := start, down
+	for _, entry := range history {
+		switch {
+		case entry.Status == "down" && !down:
+			down, carried = true, false
+			downSince = entry.CheckedAt
+		case entry.Status == "up" && down:
+			down = false
+			outages = append(outages, interval{downSince, entry.CheckedAt})
+			fresh = append(fresh, !carried)
+		}
+	}
+	if down {
+		outages = append(outages, interval{downSince, to})
+		fresh = append(fresh, !carried)
+	}
+
+	maintenance := maintenanceIntervals(db, host, start, to)
+	var downtime time.Duration
+	for i, outage := range outages {
+		remaining := subtractIntervals(outage, maintenance)
+		if len(remaining) == 0 {
+			continue
+		}
+		if fresh[i] {
+			row.Incidents++
+		}
+		downtime += totalDuration(remaining)
+	}
+
+	row.monitored = to.Sub(start) - totalDuration(maintenance)
+	row.downtime = downtime
+	row.MaintenanceMins = totalDuration(maintenance).Minutes()
+	finishUptime(&row)
+	if row.SLATarget > 0 && row.UptimePercent < row.SLATarget {
+		row.SLABreached = true
+		row.BreachedHosts = 1
+	}
+	return row, nil
+}
+
+// combineUptime aggregates host rows, weighting uptime by monitored time
+func combineUptime(key, name string, rows []UptimeRow) UptimeRow {
+	combined := UptimeRow{Key: key, Name: name}
+	var maintenance float64
+	for _, row := range rows {
+		combined.Hosts += row.Hosts
+		combined.Incidents += row.Incidents
+		combined.monitored += row.monitored
+		combined.downtime += row.downtime
+		combined.BreachedHosts += row.BreachedHosts
+		maintenance += row.MaintenanceMins
+	}
+	combined.MaintenanceMins = maintenance
+	combined.SLABreached = combined.BreachedHosts > 0
+	finishUptime(&combined)
+	return combined
+}
+
+func finishUptime(row *UptimeRow) {
+	row.DowntimeMinutes = row.downtime.Minutes()
+	row.UptimePercent = 100
+	if row.monitored > 0 {
+		row.UptimePercent = 100 * (1 - row.downtime.Seconds()/row.monitored.Seconds())
+	}
+	if row.Incidents > 0 {
+		mttr := row.downtime.Minutes() / float64(row.Incidents)
+		mtbf := (row.monitored - row.downtime).Minutes() / float64(row.Incidents)
+		row.MTTRMinutes = &mttr
+		row.MTBFMinutes = &mtbf
+	}
+}
+
+// UptimeCSVHeader and UptimeCSVRecord render report rows as CSV
+var UptimeCSVHeader = []string{"key", "name", "hosts", "uptime_percent", "incidents", "downtime_minutes",
+	"maintenance_minutes", "mttr_minutes", "mtbf_minutes", "sla_target", "sla_breached", "breached_hosts"}
+
+func UptimeCSVRecord(row UptimeRow) []string {
+	optional := func(value *float64) string {
+		if value == nil {
+			return ""
+		}
+		return fmt.Sprintf("%.2f", *value)
+	}
+	return []string{
+		row.Key,
+		row.Name,
+		fmt.Sprint(row.Hosts),
+		fmt.Sprintf("%.4f", row.UptimePercent),
+		fmt.Sprint(row.Incidents),
+		fmt.Sprintf("%.2f", row.DowntimeMinutes),
+		fmt.Sprintf("%.2f", row.MaintenanceMins),
+		optional(row.MTTRMinutes),
+		optional(row.MTBFMinutes),
+		fmt.Sprint(row.SLATarget),
+		fmt.Sprint(row.SLABreached),
+		fmt.Sprint(row.BreachedHosts),
+	}
+}
@@ -3,6 +3,7 @@ package main
 import (
 	"alerting-app/config"
 	"alerting-app/database"
+	"alerting-app/inventory"
 	"alerting-app/jobs"
 	"alerting-app/routes"
 	"log"
@@ -37,6 +38,9 @@ func main() {
 	// Connect to database
 	database.ConnectDB()
 
+	// Apply CONFIG_FILE, if set, and again on SIGHUP
+	inventory.Start()
+
 	// Setup routes
 	routes.SetupRoutes(app)
 
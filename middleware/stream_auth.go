@@ -0,0 +1,19 @@
+package middleware
+
+import (
+	"github.com/gofiber/fiber/v2"
+)
+
+// StreamProtected is Protected for streaming endpoints: browsers' EventSource cannot
+// set headers, so the token may also be passed as ?token=
+func StreamProtected() fiber.Handler {
+	protected := Protected()
+	return func(c *fiber.Ctx) error {
+		if c.Get("Authorization") == "" {
+			if token := c.Query("token"); token != "" {
+				c.Request().Header.Set("Authorization", "Bearer "+token)
+			}
+		}
+		return protected(c)
+	}
+}
@@ -7,11 +7,18 @@ import (
 // Host table with reference to CheckConfig
 type AlertChannel struct {
 	gorm.Model
-	Name    string `json:"name" gorm:"type:varchar(255);uniqueIndex"`
-	Config1 string `json:"config1"`
-	Config2 string `json:"config2"`
-	Config3 string `json:"config3"`
-	Config4 string `json:"config4"`
+	Name     string `json:"name" gorm:"type:varchar(255);uniqueIndex"`
+	Provider string `json:"provider" gorm:"type:varchar(50)"` // notifier used for delivery, defaults to Name
+	Config1  string `json:"config1"`
+	Config2  string `json:"config2"`
+	Config3  string `json:"config3"`
+	Config4  string `json:"config4"`
+
+	// Alerts for the same group key are merged into one message
+	GroupBy     string `json:"group_by" gorm:"type:varchar(50);default:none"` // "none", "channel", "device_type", "parent", "group" or "tag:<key>"
+	GroupWindow int    `json:"group_window" gorm:"default:0"`                 // seconds to wait for more alerts
+
+	ManagedBy string `json:"managed_by" gorm:"type:varchar(50)"` // "file" when owned by the configuration file
 }
 
 type SendTxt struct {
@@ -0,0 +1,36 @@
+package models
+
+import "time"
+
+// CheckResult stores every individual check; (host_id, checked_at) serves the time-range queries
+type CheckResult struct {
+	ID         uint64    `gorm:"primaryKey" json:"id"`
+	HostID     uint      `gorm:"not null;index:idx_check_results_host_time,priority:1" json:"host_id"`
+	CheckedAt  time.Time `gorm:"not null;index:idx_check_results_host_time,priority:2;index" json:"checked_at"`
+	Status     string    `gorm:"type:varchar(10)" json:"status"` // "up" or "down"
+	LatencyMs  float64   `json:"latency_ms"`
+	StatusCode int       `json:"status_code"`
+	Error      string    `gorm:"type:varchar(512)" json:"error"`
+}
+
+// Rollup resolutions
+const (
+	Rollup5m = "5m"
+	Rollup1h = "1h"
+	Rollup1d = "1d"
+)
+
+// CheckRollup aggregates the check results of one host over a fixed bucket
+type CheckRollup struct {
+	ID          uint64    `gorm:"primaryKey" json:"-"`
+	HostID      uint      `gorm:"not null;uniqueIndex:idx_check_rollups_bucket,priority:1" json:"host_id"`
+	Resolution  string    `gorm:"type:varchar(5);not null;uniqueIndex:idx_check_rollups_bucket,priority:2" json:"resolution"`
+	BucketStart time.Time `gorm:"not null;uniqueIndex:idx_check_rollups_bucket,priority:3;index" json:"bucket_start"`
+	Checks      int64     `json:"checks"`
+	UpCount     int64     `json:"up_count"`
+	UpRatio     float64   `json:"up_ratio"`
+	MinLatency  float64   `json:"min_latency_ms"`
+	AvgLatency  float64   `json:"avg_latency_ms"`
+	MaxLatency  float64   `json:"max_latency_ms"`
+	P95Latency  float64   `json:"p95_latency_ms"`
+}
@@ -0,0 +1,28 @@
+package models
+
+import "gorm.io/gorm"
+
+// HostGroup organizes hosts in a tree, e.g. site > building > floor
+type HostGroup struct {
+	gorm.Model
+	Name        string `json:"name" gorm:"type:varchar(255);uniqueIndex"`
+	ParentID    *uint  `json:"parent_id" gorm:"index"`
+	Description string `json:"description"`
+}
+
+// HostTag is a free-form key/value label such as site=ub-hq
+type HostTag struct {
+	ID     uint   `json:"-" gorm:"primaryKey"`
+	HostID uint   `json:"-" gorm:"uniqueIndex:idx_host_tags_host_key,priority:1"`
+	Key    string `json:"key" gorm:"column:tag_key;type:varchar(100);uniqueIndex:idx_host_tags_host_key,priority:2;index:idx_host_tags_key_value,priority:1"`
+	Value  string `json:"value" gorm:"column:tag_value;type:varchar(255);index:idx_host_tags_key_value,priority:2"`
+}
+
+// AlertRoute sends the alerts of matching hosts to an extra channel, on top of the host's own
+type AlertRoute struct {
+	gorm.Model
+	Name        string `json:"name"`
+	TagSelector string `json:"tag_selector"` // "site=ub-hq,floor=3"; a bare key only requires the tag to exist
+	GroupID     *uint  `json:"group_id"`     // the group and every group below it
+	ChannelName string `json:"channel_name"`
+}
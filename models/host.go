@@ -6,6 +6,14 @@ import (
 	"gorm.io/gorm"
 )
 
+// SeverityLevels ranks host severities from least to most urgent
+var SeverityLevels = map[string]int{
+	"info":     1,
+	"minor":    2,
+	"major":    3,
+	"critical": 4,
+}
+
 // CheckConfig table stores available check methods
 type CheckConfig struct {
 	ID     uint   `gorm:"primaryKey;autoIncrement"`
@@ -14,7 +22,10 @@ type CheckConfig struct {
 type DeviceType struct {
 	gorm.Model
 
-	DevType string `json:"device_type" gorm:"type:varchar(255);unique;primaryKey"`
+	DevType            string  `json:"device_type" gorm:"type:varchar(255);unique;primaryKey"`
+	EscalationPolicyID *uint   `json:"escalation_policy_id"`
+	SLATarget          float64 `json:"sla_target"`
+	ManagedBy          string  `json:"managed_by" gorm:"type:varchar(50)"` // "file" when owned by the configuration file
 }
 
 // Host table with reference to CheckConfig
@@ -42,27 +53,46 @@ type Host struct {
 	HttpBody         *string      `json:"http_body"`
 	HttpHeader       *string      `json:"http_header"`
 
-	ExpectedResponse *int `json:"expected_response"`
+	ExpectedResponse   *int       `json:"expected_response"`
+	EscalationPolicyID *uint      `json:"escalation_policy_id"`
+	ParentID           *uint      `json:"parent_id" gorm:"index"` // upstream device, e.g. the switch a camera hangs off
+	MutedUntil         *time.Time `json:"muted_until"`
+	Severity           string     `json:"severity" gorm:"type:varchar(20);default:major"` // see SeverityLevels
+	SLATarget          float64    `json:"sla_target" gorm:"default:0"`                    // uptime percent, 0 for none
+	GroupID            *uint      `json:"group_id" gorm:"index"`
+	Tags               []HostTag  `json:"tags" gorm:"foreignKey:HostID;constraint:OnDelete:CASCADE"`
+	ManagedBy          string     `json:"managed_by" gorm:"type:varchar(50)"` // "file" when owned by the configuration file
+	Version            uint       `json:"version" gorm:"not null;default:1"`  // bumped by API and configuration edits, not by checks
 }
+
+// NextHostVersion bumps Host.Version in column updates
+var NextHostVersion = gorm.Expr("version + 1")
+
+// HostHistory indexes back the GetHistory filters and its checked_at/host_name sorts
 type HostHistory struct {
 	ID           uint      `gorm:"primaryKey" json:"id"`
-	HostID       uint      `json:"host_id"`
-	HostName     string    `json:"host_name"`
-	Status       string    `json:"status"` // "up" or "down"
-	CheckedAt    time.Time `json:"checked_at"`
-	DeviceType   string    `json:"dev_type"`
+	HostID       uint      `json:"host_id" gorm:"index:idx_host_histories_host_time,priority:1"`
+	HostName     string    `json:"host_name" gorm:"type:varchar(255);index"`
+	Status       string    `json:"status" gorm:"type:varchar(10);index"` // "up" or "down"
+	CheckedAt    time.Time `json:"checked_at" gorm:"index:idx_host_histories_host_time,priority:2;index"`
+	DeviceType   string    `json:"dev_type" gorm:"type:varchar(255);index"`
 	AlertStatus  bool      `json:"alert_status"`
 	DownDuration float64   `gorm:"type:float"`
 }
 
 type UpdatedFields struct {
-	Name             string `json:"name"`
-	IP               string `json:"ip"`
-	MethodID         uint   `json:"methodId"`
-	Interval         int    `json:"interval"`
-	RetryCount       int    `json:"retry_count"`
-	NumOfRetry       int    `json:"num_of_retry"`
-	IsActive         bool   `json:"is_active"`
-	DevType          string `json:"device_type_name"`
-	ExpectedResponse *int   `json:"expected_response"`
+	Name               string  `json:"name"`
+	IP                 string  `json:"ip"`
+	MethodID           uint    `json:"methodId"`
+	Interval           int     `json:"interval"`
+	RetryCount         int     `json:"retry_count"`
+	NumOfRetry         int     `json:"num_of_retry"`
+	IsActive           bool    `json:"is_active"`
+	DevType            string  `json:"device_type_name"`
+	ExpectedResponse   *int    `json:"expected_response"`
+	EscalationPolicyID *uint   `json:"escalation_policy_id"`
+	ParentID           *uint   `json:"parent_id"`
+	Severity           string  `json:"severity"`
+	SLATarget          float64 `json:"sla_target"`
+	GroupID            *uint   `json:"group_id"`
 }
@@ -0,0 +1,49 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Incident states
+const (
+	IncidentOpen     = "open"
+	IncidentResolved = "resolved"
+)
+
+// Incident tracks one down period of a host from alert to recovery
+type Incident struct {
+	gorm.Model
+	HostID             uint       `json:"host_id" gorm:"index"`
+	HostName           string     `json:"host_name"`
+	Status             string     `json:"status" gorm:"type:varchar(20);index;default:open"`
+	OpenedAt           time.Time  `json:"opened_at"`
+	ResolvedAt         *time.Time `json:"resolved_at"`
+	AcknowledgedAt     *time.Time `json:"acknowledged_at"`
+	AcknowledgedBy     string     `json:"acknowledged_by"`
+	EscalationPolicyID *uint      `json:"escalation_policy_id"`
+	EscalationLevel    int        `json:"escalation_level" gorm:"default:0"` // number of steps already notified
+	LastNotifiedAt     *time.Time `json:"last_notified_at"`
+	PublicNote         string     `json:"public_note" gorm:"type:text"` // shown on status pages
+}
+
+// EscalationPolicy describes who gets notified while an incident stays unacknowledged
+type EscalationPolicy struct {
+	gorm.Model
+	Name            string           `json:"name" gorm:"type:varchar(255);uniqueIndex"`
+	RepeatWhileDown bool             `json:"repeat_while_down" gorm:"default:false"`
+	RepeatInterval  int              `json:"repeat_interval" gorm:"default:30"` // minutes between reminders
+	Steps           []EscalationStep `json:"steps" gorm:"foreignKey:PolicyID;constraint:OnDelete:CASCADE"`
+}
+
+// EscalationStep notifies a channel, a user or whoever is on call once the incident is DelayMinutes old
+type EscalationStep struct {
+	ID           uint   `gorm:"primaryKey" json:"id"`
+	PolicyID     uint   `json:"policy_id" gorm:"index"`
+	Position     int    `json:"position"`
+	DelayMinutes int    `json:"delay_minutes"`
+	ChannelName  string `json:"channel_name"`
+	UserID       *uint  `json:"user_id"`
+	ScheduleID   *uint  `json:"schedule_id"`
+}
@@ -0,0 +1,22 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// MaintenanceWindow covers the hosts matching every selector that is set, or every host when none is.
+// Alerts are suppressed and the time is excluded from uptime reports.
+type MaintenanceWindow struct {
+	gorm.Model
+	Name           string    `json:"name"`
+	HostID         *uint     `json:"host_id" gorm:"index"`
+	DeviceTypeName string    `json:"device_type_name" gorm:"type:varchar(255)"`
+	GroupID        *uint     `json:"group_id"`
+	TagSelector    string    `json:"tag_selector"` // see AlertRoute
+	StartsAt       time.Time `json:"starts_at" gorm:"index"`
+	EndsAt         time.Time `json:"ends_at" gorm:"index"`
+	Reason         string    `json:"reason"`
+	ManagedBy      string    `json:"managed_by" gorm:"type:varchar(50)"` // "file" when owned by the configuration file
+}
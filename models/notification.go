@@ -0,0 +1,40 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Notification delivery states
+const (
+	NotificationPending = "pending"
+	NotificationSending = "sending" // delivered inline, never picked up by the dispatcher
+	NotificationSent    = "sent"
+	NotificationFailed  = "failed"
+	NotificationGrouped = "grouped" // merged into the summary notification GroupedIntoID
+)
+
+// Notification is a persisted outbox entry, one row per alert delivery
+type Notification struct {
+	gorm.Model
+	HostID        uint       `json:"host_id" gorm:"index"`
+	HostName      string     `json:"host_name"`
+	IncidentID    *uint      `json:"incident_id" gorm:"index"`
+	ChannelName   string     `json:"channel_name" gorm:"type:varchar(255);index"`
+	Recipient     string     `json:"recipient"`                     // overrides the channel's default destination
+	Event         string     `json:"event" gorm:"type:varchar(50)"` // "down", "up", "escalation", "reminder", "digest", "batch", "report" or "test"
+	Message       string     `json:"message" gorm:"type:text"`
+	Status        string     `json:"status" gorm:"type:varchar(20);index;default:pending"`
+	Attempts      int        `json:"attempts" gorm:"default:0"`
+	MaxAttempts   int        `json:"max_attempts" gorm:"default:8"`
+	NextAttemptAt time.Time  `json:"next_attempt_at" gorm:"index"`
+	LastError     string     `json:"last_error" gorm:"type:text"`
+	Response      string     `json:"response" gorm:"type:text"`
+	SentAt        *time.Time `json:"sent_at"`
+	GroupKey      string     `json:"group_key" gorm:"type:varchar(255);index"`
+	GroupedIntoID *uint      `json:"grouped_into_id"`
+	UserID        *uint      `json:"user_id"`                       // set when addressed to a user's contact
+	Severity      string     `json:"severity"`                      // copied from the host
+	Deferred      bool       `json:"deferred" gorm:"default:false"` // held back by quiet hours
+}
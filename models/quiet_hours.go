@@ -0,0 +1,15 @@
+package models
+
+import "gorm.io/gorm"
+
+// QuietHours defers notifications below MinSeverity between Start and End (HH:MM, local to Timezone).
+// A rule applies to a channel, to a user, or to both when both are set.
+type QuietHours struct {
+	gorm.Model
+	ChannelName string `json:"channel_name" gorm:"type:varchar(255);index"`
+	UserID      *uint  `json:"user_id" gorm:"index"`
+	Start       string `json:"start"` // HH:MM
+	End         string `json:"end"`   // HH:MM, may be earlier than Start to span midnight
+	Timezone    string `json:"timezone" gorm:"default:UTC"`
+	MinSeverity string `json:"min_severity" gorm:"default:critical"` // lower severities are deferred
+}
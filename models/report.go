@@ -0,0 +1,26 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ReportDefinition describes an availability report generated on a schedule
+type ReportDefinition struct {
+	gorm.Model
+	Name        string     `json:"name" gorm:"type:varchar(255);uniqueIndex"`
+	Period      string     `json:"period" gorm:"type:varchar(20);default:weekly"` // "weekly" or "monthly"
+	GroupBy     string     `json:"group_by" gorm:"type:varchar(50);default:host"` // "host", "device_type", "group" or "tag:<key>"
+	HostIDs     string     `json:"host_ids"`                                      // comma separated, empty for all hosts
+	DeviceType  string     `json:"device_type"`
+	GroupID     *uint      `json:"group_id"`
+	TagSelector string     `json:"tag_selector"`
+	Format      string     `json:"format" gorm:"type:varchar(10);default:html"` // "html" or "pdf"
+	Recipients  string     `json:"recipients"`                                  // comma separated email addresses
+	ChannelName string     `json:"channel_name"`                                // posts a summary to the channel
+	Enabled     bool       `json:"enabled"`                                     // no column default, so false survives inserts
+	NextRunAt   time.Time  `json:"next_run_at" gorm:"index"`
+	LastRunAt   *time.Time `json:"last_run_at"`
+	LastError   string     `json:"last_error" gorm:"type:text"`
+}
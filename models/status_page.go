@@ -0,0 +1,24 @@
+package models
+
+import "gorm.io/gorm"
+
+// StatusPage is a read-only page served without authentication at /status/<slug>
+type StatusPage struct {
+	gorm.Model
+	Slug        string                `json:"slug" gorm:"type:varchar(100);uniqueIndex"`
+	Title       string                `json:"title"`
+	Description string                `json:"description" gorm:"type:text"`
+	Components  []StatusPageComponent `json:"components" gorm:"foreignKey:PageID;constraint:OnDelete:CASCADE"`
+}
+
+// StatusPageComponent shows a single host, every host of a device type or every host of a group
+// and its subgroups under a public name
+type StatusPageComponent struct {
+	ID             uint   `json:"id" gorm:"primaryKey"`
+	PageID         uint   `json:"page_id" gorm:"index"`
+	Position       int    `json:"position"`
+	HostID         *uint  `json:"host_id"`
+	DeviceTypeName string `json:"device_type_name" gorm:"type:varchar(255)"`
+	GroupID        *uint  `json:"group_id" gorm:"index"`
+	DisplayName    string `json:"display_name"`
+}
@@ -18,3 +18,44 @@ type LoginRequest struct {
 	Username string `json:"username"`
 	Password string `json:"password"`
 }
+
+// UserContact is one way of reaching a user; lower Priority is tried first
+type UserContact struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	UserID    uint      `gorm:"index;not null" json:"user_id"`
+	Type      string    `gorm:"type:varchar(20);not null" json:"type"` // "email", "telegram" or "webhook"
+	Value     string    `gorm:"not null" json:"value"`
+	Priority  int       `gorm:"default:0" json:"priority"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// OnCallSchedule rotates its members every RotationDays at HandoffTime (local to Timezone)
+type OnCallSchedule struct {
+	gorm.Model
+	Name          string           `json:"name" gorm:"type:varchar(255);uniqueIndex"`
+	Timezone      string           `json:"timezone" gorm:"default:UTC"`
+	RotationStart time.Time        `json:"rotation_start"`                    // first day of the rotation
+	HandoffTime   string           `json:"handoff_time" gorm:"default:09:00"` // HH:MM
+	RotationDays  int              `json:"rotation_days" gorm:"default:7"`
+	Members       []OnCallMember   `json:"members" gorm:"foreignKey:ScheduleID;constraint:OnDelete:CASCADE"`
+	Overrides     []OnCallOverride `json:"overrides" gorm:"foreignKey:ScheduleID;constraint:OnDelete:CASCADE"`
+}
+
+type OnCallMember struct {
+	ID         uint `gorm:"primaryKey" json:"id"`
+	ScheduleID uint `gorm:"index" json:"schedule_id"`
+	UserID     uint `json:"user_id"`
+	Position   int  `json:"position"`
+	User       User `json:"user" gorm:"foreignKey:UserID"`
+}
+
+// OnCallOverride puts a user on call for a fixed period regardless of the rotation
+type OnCallOverride struct {
+	ID         uint      `gorm:"primaryKey" json:"id"`
+	ScheduleID uint      `gorm:"index" json:"schedule_id"`
+	UserID     uint      `json:"user_id"`
+	StartsAt   time.Time `json:"starts_at"`
+	EndsAt     time.Time `json:"ends_at"`
+	User       User      `json:"user" gorm:"foreignKey:UserID"`
+}
@@ -8,6 +8,12 @@ import (
 )
 
 func SetupRoutes(app *fiber.App) {
+	// Prometheus scrape endpoint, guarded by METRICS_TOKEN instead of a user login
+	app.Get("/metrics", handlers.GetMetrics)
+
+	// Public status pages; registered here so the SPA fallback does not catch them
+	app.Get("/status/:slug", handlers.GetPublicStatusPage)
+
 	api := app.Group("/api")
 
 	// Public routes
@@ -15,6 +21,10 @@ func SetupRoutes(app *fiber.App) {
 	api.Get("/buildconfig", handlers.Configcam)
 
 	api.Get("/validate-token", handlers.ValidateToken) // Optional endpoint to check token validity
+	api.Get("/status-pages/:slug/public", handlers.GetPublicStatusPageJSON)
+
+	// Live events; the token may be passed as ?token= since EventSource cannot set headers
+	api.Get("/stream", middleware.StreamProtected(), handlers.StreamEvents)
 
 	// Protected routes group
 	protected := api.Group("")
@@ -26,9 +36,78 @@ func SetupRoutes(app *fiber.App) {
 	// protected.Get("/get-cameras", handlers.GetCamera)
 	protected.Put("/hosts/:id", handlers.UpdateHost)
 	protected.Delete("/hosts/:id", handlers.DeleteHost)
+	protected.Put("/hosts/:id/tags", handlers.SetHostTags)
+	protected.Post("/hosts/import", handlers.ImportHosts)
+	protected.Get("/hosts/export", handlers.ExportHosts)
+	protected.Get("/host-groups", handlers.GetHostGroups)
+	protected.Get("/host-groups/status", handlers.GetHostGroupStatuses)
+	protected.Post("/host-groups", handlers.CreateHostGroup)
+	protected.Put("/host-groups/:id", handlers.UpdateHostGroup)
+	protected.Delete("/host-groups/:id", handlers.DeleteHostGroup)
+	protected.Get("/host-groups/:id/status", handlers.GetHostGroupStatus)
+	protected.Post("/host-groups/:id/bulk", handlers.BulkUpdateHostGroup)
+	protected.Get("/alert-routes", handlers.GetAlertRoutes)
+	protected.Post("/alert-routes", handlers.CreateAlertRoute)
+	protected.Put("/alert-routes/:id", handlers.UpdateAlertRoute)
+	protected.Delete("/alert-routes/:id", handlers.DeleteAlertRoute)
+	protected.Get("/hosts/:id/results", handlers.GetHostResults)
 	protected.Get("/hosts", handlers.GetHosts)
+	protected.Get("/hosts/deleted", handlers.GetDeletedHosts)
+	protected.Get("/hosts/:id", handlers.GetHost)
+	protected.Post("/hosts/:id/restore", handlers.RestoreHost)
+	protected.Delete("/hosts/:id/purge", handlers.PurgeHost)
+	protected.Patch("/hosts/:id", handlers.PatchHost)
 	protected.Get("/devtype", handlers.GetDevType)
 	protected.Get("/check-method", handlers.GetMethod)
 	protected.Get("/check-alert", handlers.GetAlert)
+	protected.Get("/alert-channels", handlers.GetAlertChannels)
+	protected.Get("/alert-channels/:id", handlers.GetAlertChannel)
+	protected.Post("/alert-channels", handlers.CreateAlertChannel)
+	protected.Put("/alert-channels/:id", handlers.UpdateAlertChannel)
+	protected.Delete("/alert-channels/:id", handlers.DeleteAlertChannel)
+	protected.Post("/alert-channels/:id/test", handlers.TestAlertChannel)
 	protected.Get("/host-history", handlers.GetHistory)
+	protected.Get("/notifications", handlers.GetNotifications)
+	protected.Post("/notifications/:id/resend", handlers.ResendNotification)
+	protected.Get("/incidents", handlers.GetIncidents)
+	protected.Post("/incidents/:id/ack", handlers.AcknowledgeIncident)
+	protected.Put("/incidents/:id/public-note", handlers.UpdateIncidentPublicNote)
+	protected.Get("/escalation-policies", handlers.GetEscalationPolicies)
+	protected.Post("/escalation-policies", handlers.CreateEscalationPolicy)
+	protected.Put("/escalation-policies/:id", handlers.UpdateEscalationPolicy)
+	protected.Delete("/escalation-policies/:id", handlers.DeleteEscalationPolicy)
+	protected.Post("/escalation-policies/:id/attach", handlers.AttachEscalationPolicy)
+	protected.Get("/users", handlers.GetUsers)
+	protected.Get("/users/:id/contacts", handlers.GetUserContacts)
+	protected.Post("/users/:id/contacts", handlers.CreateUserContact)
+	protected.Delete("/users/:id/contacts/:contactId", handlers.DeleteUserContact)
+	protected.Get("/oncall-schedules", handlers.GetOnCallSchedules)
+	protected.Post("/oncall-schedules", handlers.CreateOnCallSchedule)
+	protected.Put("/oncall-schedules/:id", handlers.UpdateOnCallSchedule)
+	protected.Delete("/oncall-schedules/:id", handlers.DeleteOnCallSchedule)
+	protected.Post("/oncall-schedules/:id/overrides", handlers.CreateOnCallOverride)
+	protected.Delete("/oncall-schedules/:id/overrides/:overrideId", handlers.DeleteOnCallOverride)
+	protected.Get("/oncall", handlers.GetOnCall)
+	protected.Get("/quiet-hours", handlers.GetQuietHours)
+	protected.Post("/quiet-hours", handlers.CreateQuietHours)
+	protected.Put("/quiet-hours/:id", handlers.UpdateQuietHours)
+	protected.Delete("/quiet-hours/:id", handlers.DeleteQuietHours)
+	protected.Get("/maintenance-windows", handlers.GetMaintenanceWindows)
+	protected.Post("/maintenance-windows", handlers.CreateMaintenanceWindow)
+	protected.Put("/maintenance-windows/:id", handlers.UpdateMaintenanceWindow)
+	protected.Delete("/maintenance-windows/:id", handlers.DeleteMaintenanceWindow)
+	protected.Get("/reports/uptime", handlers.GetUptimeReport)
+	protected.Get("/report-definitions", handlers.GetReportDefinitions)
+	protected.Post("/report-definitions", handlers.CreateReportDefinition)
+	protected.Put("/report-definitions/:id", handlers.UpdateReportDefinition)
+	protected.Delete("/report-definitions/:id", handlers.DeleteReportDefinition)
+	protected.Get("/report-definitions/:id/render", handlers.RenderReportDefinition)
+	protected.Post("/report-definitions/:id/run", handlers.RunReportDefinitionNow)
+	protected.Get("/status-pages", handlers.GetStatusPages)
+	protected.Post("/status-pages", handlers.CreateStatusPage)
+	protected.Put("/status-pages/:id", handlers.UpdateStatusPage)
+	protected.Delete("/status-pages/:id", handlers.DeleteStatusPage)
+	protected.Get("/oncall-schedules/:id/oncall", handlers.GetOnCall)
+	protected.Post("/config/reload", handlers.ReloadConfig)
+	protected.Get("/config/sync", handlers.GetConfigSync)
 }